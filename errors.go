@@ -0,0 +1,99 @@
+package meddler
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNoRowsAffected is returned by Delete, DeleteByPK, and SoftDelete when
+// no row matched the primary key.
+var ErrNoRowsAffected = errors.New("meddler: no rows affected")
+
+// ErrStaleObject is returned by the update functions when the record has a
+// version column and no row matched both its primary key and its version,
+// meaning the row was changed (or deleted) since the record was loaded.
+var ErrStaleObject = errors.New("meddler: stale object")
+
+// ErrNoPrimaryKey is wrapped by the errors of functions that need a
+// primary key field when the struct has none. Use errors.Is to check for
+// it, e.g.
+//
+//	if errors.Is(err, meddler.ErrNoPrimaryKey) {
+var ErrNoPrimaryKey = errors.New("no primary key field found")
+
+// ErrZeroPK is wrapped by the errors of functions that need a record
+// with a non-zero primary key, e.g. Update and Delete, when it is zero.
+var ErrZeroPK = errors.New("primary key must be non-zero")
+
+// ErrUnmatchedColumn is wrapped in a ColumnError when a column has no
+// struct field, e.g. a column of a query result when the Unmatched mode
+// of the Database is UnmatchedStrict.
+var ErrUnmatchedColumn = errors.New("not found in struct")
+
+// ErrMissingColumn is wrapped in a ColumnError when the Unmatched mode of
+// the Database is UnmatchedStrict and a query result has no column for
+// a struct field.
+var ErrMissingColumn = errors.New("not found in result")
+
+// ColumnError records an error about a single column, e.g. from its
+// meddler or from a column name given to UpdateColumns.
+type ColumnError struct {
+	Column string // the name of the column
+	Op     string // the meddler function that failed, e.g. "meddler.UpdateColumns"
+	Err    error  // what went wrong
+}
+
+func (err *ColumnError) Error() string {
+	return fmt.Sprintf("%s: column [%s]: %v", err.Op, err.Column, err.Err)
+}
+
+// Unwrap returns the underlying error, for use with errors.Is and errors.As.
+func (err *ColumnError) Unwrap() error {
+	return err.Err
+}
+
+// opErr adds the name of the meddler function that failed to an error,
+// such as one of the sentinel errors above.
+type opErr struct {
+	op  string
+	err error
+}
+
+func (err *opErr) Error() string {
+	return fmt.Sprintf("%s: %v", err.op, err.err)
+}
+
+func (err *opErr) Unwrap() error {
+	return err.err
+}
+
+type dbErr struct {
+	msg string
+	err error
+}
+
+func (err *dbErr) Error() string {
+	return fmt.Sprintf("%s: %v", err.msg, err.err)
+}
+
+// Unwrap returns the error from the database driver.
+func (err *dbErr) Unwrap() error {
+	return err.err
+}
+
+// DriverErr returns the original error as returned by the database driver
+// if the error comes from the driver, with the second value set to true.
+// Otherwise, it returns err itself with false as second value.
+func DriverErr(err error) (error, bool) {
+	for e := err; e != nil; {
+		if dbe, ok := e.(*dbErr); ok {
+			return dbe.err, true
+		}
+		wrapper, ok := e.(interface{ Unwrap() error })
+		if !ok {
+			break
+		}
+		e = wrapper.Unwrap()
+	}
+	return err, false
+}
@@ -0,0 +1,76 @@
+//go:build go1.13
+// +build go1.13
+
+package meddler
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+var errRejected = errors.New("rejected")
+
+type rejectMeddler struct {
+	IdentityMeddler
+}
+
+func (rejectMeddler) PreWrite(field interface{}) (interface{}, error) {
+	return nil, errRejected
+}
+
+type Unkeyed struct {
+	Name string `meddler:"name"`
+}
+
+func TestErrorTypes(t *testing.T) {
+	once.Do(setup)
+
+	err := Update(db, "person", &Person{Name: "Nobody"})
+	if !errors.Is(err, ErrZeroPK) {
+		t.Errorf("Update with a zero key: want %v, got %v", ErrZeroPK, err)
+	}
+	if err == nil || err.Error() != "meddler.Update: primary key must be non-zero" {
+		t.Errorf("Unexpected message for Update with a zero key: %v", err)
+	}
+	if err := Delete(db, "person", &Unkeyed{}); !errors.Is(err, ErrNoPrimaryKey) {
+		t.Errorf("Delete without a key: want %v, got %v", ErrNoPrimaryKey, err)
+	}
+	if err := Load(db, "person", &Unkeyed{}, 1); !errors.Is(err, ErrNoPrimaryKey) {
+		t.Errorf("Load without a key: want %v, got %v", ErrNoPrimaryKey, err)
+	}
+
+	var colErr *ColumnError
+	err = UpdateColumns(db, "person", &Person{ID: 1}, "nonesuch")
+	if !errors.As(err, &colErr) || colErr.Column != "nonesuch" || colErr.Op != "meddler.UpdateColumns" {
+		t.Errorf("UpdateColumns with an unknown column: want a ColumnError, got %#v", err)
+	}
+
+	// errors from meddlers are wrapped with their column
+	d := *SQLite
+	d.Registry = NewRegistry()
+	d.Registry.Register("reject", rejectMeddler{})
+	type Rejected struct {
+		ID   int64  `meddler:"id,pk"`
+		Name string `meddler:"name,reject"`
+	}
+	err = d.Insert(db, "person", &Rejected{Name: "Mallory"})
+	if !errors.Is(err, errRejected) {
+		t.Errorf("Insert with a failing meddler: want %v, got %v", errRejected, err)
+	}
+	if !errors.As(err, &colErr) || colErr.Column != "name" {
+		t.Errorf("Insert with a failing meddler: want a ColumnError for name, got %#v", err)
+	}
+
+	// driver errors can be found with errors.As as well as DriverErr
+	err = Insert(db, "invalid", &Person{Name: "Mallory"})
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		t.Errorf("Insert into an invalid table: want a sqlite3 error, got %#v", err)
+	}
+	wrapped := &ColumnError{Column: "name", Op: "meddler.Test", Err: err}
+	if driverErr, ok := DriverErr(wrapped); !ok || driverErr != sqliteErr {
+		t.Errorf("DriverErr through a ColumnError: want %v, got %v", sqliteErr, driverErr)
+	}
+}
@@ -0,0 +1,106 @@
+package meddler
+
+import (
+	"context"
+	"database/sql"
+)
+
+// BeforeInserter is implemented by structs that need to run code before
+// they are inserted, e.g. to set a creation time or validate fields. It is
+// called by Insert, InsertAll, Upsert, and Save (when it inserts). If it
+// returns an error, the record is not inserted and the error is returned.
+type BeforeInserter interface {
+	BeforeInsert(db DB) error
+}
+
+// AfterInserter is implemented by structs that need to run code after they
+// have been inserted. The primary key generated by the database, if any,
+// has already been set. An error is returned by the calling function,
+// but the record has already been inserted.
+type AfterInserter interface {
+	AfterInsert(db DB) error
+}
+
+// BeforeUpdater is implemented by structs that need to run code before
+// they are updated. It is called by Update, UpdateColumns, UpdateChanged,
+// and Save (when it updates). If it returns an error, the record is not
+// updated and the error is returned.
+type BeforeUpdater interface {
+	BeforeUpdate(db DB) error
+}
+
+// AfterUpdater is implemented by structs that need to run code after they
+// have been updated. An error is returned by the calling function, but the
+// record has already been updated.
+type AfterUpdater interface {
+	AfterUpdate(db DB) error
+}
+
+// AfterLoader is implemented by structs that need to run code after they
+// have been loaded, e.g. to compute derived fields. It is called for every
+// struct filled in by Load, QueryRow, QueryAll, and the Scan functions.
+// If it returns an error, the error is returned by the calling function.
+type AfterLoader interface {
+	AfterLoad() error
+}
+
+func beforeInsert(ctx context.Context, db DBContext, src interface{}) error {
+	if hook, ok := src.(BeforeInserter); ok {
+		return hook.BeforeInsert(hookDB(ctx, db))
+	}
+	return nil
+}
+
+func afterInsert(ctx context.Context, db DBContext, src interface{}) error {
+	if hook, ok := src.(AfterInserter); ok {
+		return hook.AfterInsert(hookDB(ctx, db))
+	}
+	return nil
+}
+
+func beforeUpdate(ctx context.Context, db DBContext, src interface{}) error {
+	if hook, ok := src.(BeforeUpdater); ok {
+		return hook.BeforeUpdate(hookDB(ctx, db))
+	}
+	return nil
+}
+
+func afterUpdate(ctx context.Context, db DBContext, src interface{}) error {
+	if hook, ok := src.(AfterUpdater); ok {
+		return hook.AfterUpdate(hookDB(ctx, db))
+	}
+	return nil
+}
+
+func afterLoad(dst interface{}) error {
+	if hook, ok := dst.(AfterLoader); ok {
+		return hook.AfterLoad()
+	}
+	return nil
+}
+
+// hookDB returns the DB to hand to a hook, which runs its queries on the
+// DB given by the caller with ctx, so they are cancelled along with the
+// calling function.
+func hookDB(ctx context.Context, db DBContext) DB {
+	return contextDB{ctx: ctx, db: db}
+}
+
+// contextDB adapts a DBContext to the DB interface, running every query
+// with the same context.
+type contextDB struct {
+	ctx context.Context
+	db  DBContext
+}
+
+func (db contextDB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return db.db.ExecContext(db.ctx, query, args...)
+}
+
+func (db contextDB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return db.db.QueryContext(db.ctx, query, args...)
+}
+
+func (db contextDB) QueryRow(query string, args ...interface{}) *sql.Row {
+	return db.db.QueryRowContext(db.ctx, query, args...)
+}
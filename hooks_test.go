@@ -0,0 +1,128 @@
+package meddler
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type HookedPerson struct {
+	ID      int64     `meddler:"id,pk"`
+	Name    string    `meddler:"name"`
+	Email   string    `meddler:"Email"`
+	Opened  time.Time `meddler:"opened,utctime"`
+	Display string    `meddler:"-"`
+	calls   []string
+}
+
+var errNoName = errors.New("name is required")
+
+func (p *HookedPerson) BeforeInsert(db DB) error {
+	p.calls = append(p.calls, "BeforeInsert")
+	if p.Name == "" {
+		return errNoName
+	}
+	if p.Opened.IsZero() {
+		p.Opened = when
+	}
+	return nil
+}
+
+func (p *HookedPerson) AfterInsert(db DB) error {
+	p.calls = append(p.calls, "AfterInsert")
+	return nil
+}
+
+func (p *HookedPerson) BeforeUpdate(db DB) error {
+	p.calls = append(p.calls, "BeforeUpdate")
+	if p.Name == "" {
+		return errNoName
+	}
+	return nil
+}
+
+func (p *HookedPerson) AfterUpdate(db DB) error {
+	p.calls = append(p.calls, "AfterUpdate")
+	return nil
+}
+
+func (p *HookedPerson) AfterLoad() error {
+	p.calls = append(p.calls, "AfterLoad")
+	p.Display = p.Name + " <" + p.Email + ">"
+	return nil
+}
+
+func TestHooks(t *testing.T) {
+	once.Do(setup)
+
+	p := &HookedPerson{Name: "Alice", Email: "alice@alice.com"}
+	if err := Save(db, "person", p); err != nil {
+		t.Errorf("Save error inserting Alice: %v", err)
+	}
+	if !p.Opened.Equal(when) {
+		t.Errorf("BeforeInsert did not set Opened, found %v", p.Opened)
+	}
+	p.Email = "alice@example.com"
+	if err := Save(db, "person", p); err != nil {
+		t.Errorf("Save error updating Alice: %v", err)
+	}
+	expected := []string{"BeforeInsert", "AfterInsert", "BeforeUpdate", "AfterUpdate"}
+	if !reflect.DeepEqual(p.calls, expected) {
+		t.Errorf("Expected hooks %v, found %v", expected, p.calls)
+	}
+
+	loaded := new(HookedPerson)
+	if err := Load(db, "person", loaded, p.ID); err != nil {
+		t.Errorf("Load error on Alice: %v", err)
+	}
+	if loaded.Display != "Alice <alice@example.com>" {
+		t.Errorf("AfterLoad did not set Display, found %q", loaded.Display)
+	}
+
+	var lst []*HookedPerson
+	if err := QueryAll(db, &lst, "select * from person"); err != nil {
+		t.Errorf("QueryAll error: %v", err)
+	}
+	if len(lst) != 1 || !reflect.DeepEqual(lst[0].calls, []string{"AfterLoad"}) {
+		t.Errorf("Expected AfterLoad to run once per record, found %v", lst)
+	}
+
+	// an error from a before hook aborts the operation
+	p.Name = ""
+	if err := Update(db, "person", p); err != errNoName {
+		t.Errorf("Update with no name: want %v, got %v", errNoName, err)
+	}
+	if err := Insert(db, "person", &HookedPerson{}); err != errNoName {
+		t.Errorf("Insert with no name: want %v, got %v", errNoName, err)
+	}
+	var count int
+	if err := db.QueryRow("select count(*) from person where name = 'Alice'").Scan(&count); err != nil || count != 1 {
+		t.Errorf("Expected Alice to be unchanged, found %d rows (%v)", count, err)
+	}
+
+	db.Exec("delete from person")
+}
+
+func TestHookDB(t *testing.T) {
+	once.Do(setup)
+
+	// hooks get a DB that runs its queries with the context, even when
+	// the caller passed a *sql.DB
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	wrapped := hookDB(ctx, withContext(db))
+	if _, err := wrapped.Exec("delete from person"); err != context.Canceled {
+		t.Errorf("Exec with canceled context: want %v, got %v", context.Canceled, err)
+	}
+
+	// queries on a DB without context support still reach it
+	rec := new(recordingDB)
+	if _, err := hookDB(ctx, withContext(rec)).Exec("delete from person"); err != nil {
+		t.Errorf("Exec on recordingDB: %v", err)
+	}
+	if len(rec.queries) != 1 {
+		t.Errorf("Expected the query to reach recordingDB, found %q", rec.queries)
+	}
+}
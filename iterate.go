@@ -0,0 +1,81 @@
+//go:build go1.23
+// +build go1.23
+
+package meddler
+
+import (
+	"context"
+	"database/sql"
+	"iter"
+	"reflect"
+)
+
+// Iterate performs the given query with the given arguments and returns an
+// iterator over the result rows, so large results can be processed one row
+// at a time instead of being read into a slice:
+//
+//	for p, err := range meddler.For[Person](meddler.PostgreSQL).Iterate(db, "SELECT * FROM person") {
+//		if err != nil {
+//			return err
+//		}
+//		// use p
+//	}
+//
+// The query runs when the iteration starts, and the rows are closed when it
+// ends, including when the loop is left early. An error ends the iteration
+// after it is yielded. Each row is scanned into a new *T.
+func (t Typed[T]) Iterate(db DB, query string, args ...interface{}) iter.Seq2[*T, error] {
+	return t.IterateContext(context.Background(), withContext(db), query, args...)
+}
+
+// IterateContext is like Iterate, but runs the query with the given context.
+func (t Typed[T]) IterateContext(ctx context.Context, db DBContext, query string, args ...interface{}) iter.Seq2[*T, error] {
+	return func(yield func(*T, error) bool) {
+		// get the list of struct fields
+		data, err := t.d.getFields(reflect.TypeOf((*T)(nil)))
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		// perform the query
+		rows, err := t.d.runQueryContext(ctx, db, query, args...)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		// make sure we always close rows
+		defer rows.Close()
+
+		// get the sql columns
+		columns, err := rows.Columns()
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		for {
+			dst := new(T)
+			if err := t.d.scanRow(data, rows, dst, columns); err != nil {
+				if err != sql.ErrNoRows {
+					yield(nil, err)
+				}
+				return
+			}
+			if !yield(dst, nil) {
+				return
+			}
+		}
+	}
+}
+
+// IterateT using the Default Database type
+func IterateT[T any](db DB, query string, args ...interface{}) iter.Seq2[*T, error] {
+	return For[T](Default).Iterate(db, query, args...)
+}
+
+// IterateTContext using the Default Database type
+func IterateTContext[T any](ctx context.Context, db DBContext, query string, args ...interface{}) iter.Seq2[*T, error] {
+	return For[T](Default).IterateContext(ctx, db, query, args...)
+}
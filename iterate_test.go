@@ -0,0 +1,57 @@
+//go:build go1.23
+// +build go1.23
+
+package meddler
+
+import (
+	"context"
+	"testing"
+)
+
+func TestIterateT(t *testing.T) {
+	once.Do(setup)
+	insertAliceBob(t)
+
+	var names []string
+	for p, err := range IterateT[Person](db, "select * from person order by id") {
+		if err != nil {
+			t.Errorf("IterateT error: %v", err)
+			break
+		}
+		names = append(names, p.Name)
+	}
+	if len(names) != 2 || names[0] != "Alice" || names[1] != "Bob" {
+		t.Errorf("IterateT: expected Alice and Bob, found %v", names)
+	}
+
+	// leaving the loop early closes the rows
+	count := 0
+	for _, err := range For[Person](SQLite).Iterate(db, "select * from person order by id") {
+		if err != nil {
+			t.Errorf("Iterate error: %v", err)
+		}
+		count++
+		break
+	}
+	if count != 1 {
+		t.Errorf("Iterate: expected to stop after 1 row, found %d", count)
+	}
+	if inUse := db.Stats().InUse; inUse != 0 {
+		t.Errorf("Iterate: expected rows to be closed after break, found %d connections in use", inUse)
+	}
+
+	// errors are yielded once and end the iteration
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	count = 0
+	for p, err := range IterateTContext[Person](ctx, db, "select * from person") {
+		if err == nil || p != nil {
+			t.Errorf("IterateTContext with cancelled context: want error, got %v, %v", p, err)
+		}
+		count++
+	}
+	if count != 1 {
+		t.Errorf("IterateTContext with cancelled context: expected 1 error, found %d", count)
+	}
+	db.Exec("delete from person")
+}
@@ -1,117 +1,238 @@
 package meddler
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"reflect"
 	"strings"
+	"time"
 )
 
-type dbErr struct {
-	msg string
-	err error
+// DB is a generic database interface, matching both *sql.Db and *sql.Tx
+type DB interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
 }
 
-func (err *dbErr) Error() string {
-	return fmt.Sprintf("%s: %v", err.msg, err.err)
+// DBContext is a generic database interface with context support, matching
+// both *sql.DB and *sql.Tx
+type DBContext interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
 }
 
-// DriverErr returns the original error as returned by the database driver
-// if the error comes from the driver, with the second value set to true.
-// Otherwise, it returns err itself with false as second value.
-func DriverErr(err error) (error, bool) {
-	if dbe, ok := err.(*dbErr); ok {
-		return dbe.err, true
-	}
-	return err, false
+// noContext adapts a DB without context support to the DBContext interface.
+// The context is ignored.
+type noContext struct {
+	DB
 }
 
-// DB is a generic database interface, matching both *sql.Db and *sql.Tx
-type DB interface {
-	Exec(query string, args ...interface{}) (sql.Result, error)
-	Query(query string, args ...interface{}) (*sql.Rows, error)
-	QueryRow(query string, args ...interface{}) *sql.Row
+func (db noContext) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return db.Exec(query, args...)
+}
+
+func (db noContext) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return db.Query(query, args...)
+}
+
+func (db noContext) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return db.QueryRow(query, args...)
+}
+
+// withContext returns db as a DBContext, using its own context-aware
+// methods when it has them.
+func withContext(db DB) DBContext {
+	if dbc, ok := db.(DBContext); ok {
+		return dbc
+	}
+	return noContext{db}
 }
 
 // Load loads a record using a query for the primary key field.
+// A struct with a composite primary key needs one value per key field,
+// in the order the fields appear in the struct.
+// If the struct has a field tagged softdelete, rows that have been soft
+// deleted are skipped; see LoadWithDeleted.
 // Returns sql.ErrNoRows if not found.
-func (d *Database) Load(db DB, table string, dst interface{}, pk int64) error {
+func (d *Database) Load(db DB, table string, dst interface{}, pk ...interface{}) error {
+	return d.LoadContext(context.Background(), withContext(db), table, dst, pk...)
+}
+
+// LoadContext is like Load, but runs the query with the given context.
+func (d *Database) LoadContext(ctx context.Context, db DBContext, table string, dst interface{}, pk ...interface{}) error {
+	return d.load(ctx, db, "meddler.Load", table, dst, pk, false)
+}
+
+// Load using the Default Database type
+func Load(db DB, table string, dst interface{}, pk ...interface{}) error {
+	return Default.Load(db, table, dst, pk...)
+}
+
+// LoadContext using the Default Database type
+func LoadContext(ctx context.Context, db DBContext, table string, dst interface{}, pk ...interface{}) error {
+	return Default.LoadContext(ctx, db, table, dst, pk...)
+}
+
+// LoadWithDeleted is like Load, but also finds rows that have been soft
+// deleted.
+func (d *Database) LoadWithDeleted(db DB, table string, dst interface{}, pk ...interface{}) error {
+	return d.LoadWithDeletedContext(context.Background(), withContext(db), table, dst, pk...)
+}
+
+// LoadWithDeletedContext is like LoadWithDeleted, but runs the query with the given context.
+func (d *Database) LoadWithDeletedContext(ctx context.Context, db DBContext, table string, dst interface{}, pk ...interface{}) error {
+	return d.load(ctx, db, "meddler.LoadWithDeleted", table, dst, pk, true)
+}
+
+// LoadWithDeleted using the Default Database type
+func LoadWithDeleted(db DB, table string, dst interface{}, pk ...interface{}) error {
+	return Default.LoadWithDeleted(db, table, dst, pk...)
+}
+
+// LoadWithDeletedContext using the Default Database type
+func LoadWithDeletedContext(ctx context.Context, db DBContext, table string, dst interface{}, pk ...interface{}) error {
+	return Default.LoadWithDeletedContext(ctx, db, table, dst, pk...)
+}
+
+func (d *Database) load(ctx context.Context, db DBContext, op, table string, dst interface{}, pk []interface{}, withDeleted bool) error {
 	columns, err := d.ColumnsQuoted(dst, true)
 	if err != nil {
 		return err
 	}
 
 	// make sure we have a primary key field
-	pkName, _, err := d.PrimaryKey(dst)
+	pkNames, _, err := d.primaryKeyFields(dst)
 	if err != nil {
 		return err
 	}
-	if pkName == "" {
-		return fmt.Errorf("meddler.Load: no primary key field found")
+	if len(pkNames) == 0 {
+		return &opErr{op: op, err: ErrNoPrimaryKey}
+	}
+	if len(pk) != len(pkNames) {
+		return fmt.Errorf("%s: primary key has %d fields, but %d values were given", op, len(pkNames), len(pk))
+	}
+	where := d.pkWhere(pkNames, 1)
+	if !withDeleted {
+		deleted, err := d.softDeleteColumn(dst)
+		if err != nil {
+			return err
+		}
+		if deleted != "" {
+			where += fmt.Sprintf(" AND %s IS NULL", d.quoted(deleted))
+		}
 	}
 
 	// run the query
-	q := fmt.Sprintf("SELECT %s FROM %s WHERE %s = %s", columns, d.quoted(table), d.quoted(pkName), d.Placeholder)
+	q := fmt.Sprintf("SELECT %s FROM %s WHERE %s", columns, d.quoted(table), where)
 
-	rows, err := d.runQuery(db, q, pk)
+	rows, err := d.runQueryContext(ctx, db, q, pkArgs(pk)...)
 	if err != nil {
-		return &dbErr{msg: "meddler.Load: DB error in Query", err: err}
+		return &dbErr{msg: op + ": DB error in Query", err: err}
 	}
 
 	// scan the row
 	return d.ScanRow(rows, dst)
 }
 
-// Load using the Default Database type
-func Load(db DB, table string, dst interface{}, pk int64) error {
-	return Default.Load(db, table, dst, pk)
-}
-
 // Insert performs an INSERT query for the given record.
-// If the record has a primary key flagged, it must be zero, and it
-// will be set to the newly-allocated primary key value from the database
-// as returned by LastInsertId.
+// If the record has a primary key flagged and it is zero, it is left to
+// the database to generate: it is omitted from the query and set to the
+// newly-allocated value as returned by LastInsertId (or as selected by
+// IDStrategy or UseReturningToGetID). A non-zero primary key is inserted
+// along with the other columns. A version column that is zero is set to 1,
+// columns tagged updated are set to the current time, and so are columns
+// tagged created unless they already have a value. If the insert fails,
+// these columns keep their old values.
 func (d *Database) Insert(db DB, table string, src interface{}) error {
-	pkName, pkValue, err := d.PrimaryKey(src)
+	return d.InsertContext(context.Background(), withContext(db), table, src)
+}
+
+// InsertContext is like Insert, but runs the query with the given context.
+func (d *Database) InsertContext(ctx context.Context, db DBContext, table string, src interface{}) error {
+	if err := beforeInsert(ctx, db, src); err != nil {
+		return err
+	}
+	// the record only keeps the values set here if the insert succeeds
+	restore, err := d.saveWritten(src)
 	if err != nil {
 		return err
 	}
-	if pkName != "" && pkValue != 0 {
-		return fmt.Errorf("meddler.Insert: primary key must be zero")
+	done := false
+	defer func() {
+		if !done {
+			restore()
+		}
+	}()
+	if err := d.initVersion(src); err != nil {
+		return err
+	}
+	if _, err := d.setTimestamps(src, true); err != nil {
+		return err
+	}
+
+	pkNames, pkFields, err := d.primaryKeyFields(src)
+	if err != nil {
+		return err
 	}
 
+	// a single zero primary key is generated by the database, anything
+	// else was chosen by the caller and is inserted with the other columns
+	generated := len(pkNames) == 1 && pkFields[0].IsZero()
+	strategy := d.idStrategy()
+	if generated && strategy == IDLastInsertID && !isIntegerKind(pkFields[0].Type()) {
+		return fmt.Errorf("meddler.Insert: primary key %s is zero, but only integer keys can be retrieved with LastInsertId", pkNames[0])
+	}
+	includePk := !generated
+
 	// gather the query parts
-	namesPart, err := d.ColumnsQuoted(src, false)
+	namesPart, err := d.ColumnsQuoted(src, includePk)
 	if err != nil {
 		return err
 	}
-	valuesPart, err := d.PlaceholdersString(src, false)
+	valuesPart, err := d.PlaceholdersString(src, includePk)
 	if err != nil {
 		return err
 	}
-	values, err := d.Values(src, false)
+	values, err := d.Values(src, includePk)
 	if err != nil {
 		return err
 	}
 
 	// run the query
-	q := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", d.quoted(table), namesPart, valuesPart)
-	if d.UseReturningToGetID && pkName != "" {
-		q += " RETURNING " + d.quoted(pkName)
-		var newPk int64
-
-		row, err := d.runQueryRow(db, q, values...)
-		if err != nil {
-			return err
+	output := ""
+	if generated && strategy == IDOutputInserted {
+		output = " OUTPUT INSERTED." + d.quoted(pkNames[0])
+	}
+	q := fmt.Sprintf("INSERT INTO %s (%s)%s VALUES (%s)", d.quoted(table), namesPart, output, valuesPart)
+	if generated && (strategy == IDReturning || strategy == IDOutputInserted) {
+		if strategy == IDReturning {
+			q += " RETURNING " + d.quoted(pkNames[0])
 		}
-		err = row.Scan(&newPk)
-		if err != nil {
+		var newPk interface{}
+
+		if err := d.runQueryRowContext(ctx, db, &newPk, q, values...); err != nil {
 			return &dbErr{msg: "meddler.Insert: DB error in QueryRow", err: err}
 		}
 		if err = d.SetPrimaryKey(src, newPk); err != nil {
-			return fmt.Errorf("meddler.Insert: Error saving updated pk: %v", err)
+			return &opErr{op: "meddler.Insert", err: err}
+		}
+	} else if generated && strategy == IDReturningInto {
+		dest := returnedKeyDest(pkFields[0].Type())
+		q += fmt.Sprintf(" RETURNING %s INTO %s", d.quoted(pkNames[0]), d.placeholder(len(values)+1))
+		values = append(values, sql.Out{Dest: dest})
+
+		if _, err := d.runExecContext(ctx, db, q, values...); err != nil {
+			return &dbErr{msg: "meddler.Insert: DB error in Exec", err: err}
+		}
+		if err = d.SetPrimaryKey(src, reflect.ValueOf(dest).Elem().Interface()); err != nil {
+			return &opErr{op: "meddler.Insert", err: err}
 		}
-	} else if pkName != "" {
-		result, err := d.runExec(db, q, values...)
+	} else if generated {
+		result, err := d.runExecContext(ctx, db, q, values...)
 		if err != nil {
 			return &dbErr{msg: "meddler.Insert: DB error in Exec", err: err}
 		}
@@ -122,16 +243,20 @@ func (d *Database) Insert(db DB, table string, src interface{}) error {
 			return &dbErr{msg: "meddler.Insert: DB error getting new primary key value", err: err}
 		}
 		if err = d.SetPrimaryKey(src, newPk); err != nil {
-			return fmt.Errorf("meddler.Insert: Error saving updated pk: %v", err)
+			return &opErr{op: "meddler.Insert", err: err}
 		}
 	} else {
-		// no primary key, so no need to lookup new value
-		if _, err := d.runExec(db, q, values...); err != nil {
+		// no generated primary key, so no need to lookup new value
+		if _, err := d.runExecContext(ctx, db, q, values...); err != nil {
 			return &dbErr{msg: "meddler.Insert: DB error in Exec", err: err}
 		}
 	}
+	done = true
 
-	return nil
+	if err := d.takeSnapshot(src, nil); err != nil {
+		return err
+	}
+	return afterInsert(ctx, db, src)
 }
 
 // Insert using the Default Database type
@@ -139,54 +264,368 @@ func Insert(db DB, table string, src interface{}) error {
 	return Default.Insert(db, table, src)
 }
 
+// InsertContext using the Default Database type
+func InsertContext(ctx context.Context, db DBContext, table string, src interface{}) error {
+	return Default.InsertContext(ctx, db, table, src)
+}
+
+// returnedKeyDest returns a pointer suitable as the sql.Out destination
+// of a generated primary key of type t.
+func returnedKeyDest(t reflect.Type) interface{} {
+	switch {
+	case isIntegerKind(t):
+		return new(int64)
+	case t.Kind() == reflect.String:
+		return new(string)
+	case t.Kind() == reflect.Array:
+		return new([]byte)
+	default:
+		return new(interface{})
+	}
+}
+
+// InsertAll performs INSERT queries for all of the records in src, which
+// must be a slice of pointers to structs (or a pointer to one). Records are
+// inserted with multi-row VALUES lists, as many per query as MaxPlaceholders
+// and MaxInsertRows allow, so a large slice takes several queries; use a
+// transaction if they must succeed or fail together.
+//
+// Primary keys follow the same rules as Insert, and must be either zero
+// in every record or non-zero in every record. Zero keys are generated by
+// the database, and are written back to the records only if the database
+// uses RETURNING (see IDStrategy), as the other strategies cannot report
+// more than one value in order.
+//
+// Versions and times are set as with Insert. If a query fails, the records
+// it did not insert keep their old versions and times.
+func (d *Database) InsertAll(db DB, table string, src interface{}) error {
+	return d.InsertAllContext(context.Background(), withContext(db), table, src)
+}
+
+// InsertAllContext is like InsertAll, but runs the queries with the given context.
+func (d *Database) InsertAllContext(ctx context.Context, db DBContext, table string, src interface{}) error {
+	// make sure src is an appropriate type
+	sliceVal := reflect.ValueOf(src)
+	if sliceVal.Kind() == reflect.Ptr {
+		sliceVal = sliceVal.Elem()
+	}
+	if sliceVal.Kind() != reflect.Slice {
+		return fmt.Errorf("meddler.InsertAll called with non-slice: %T", src)
+	}
+	if sliceVal.Len() == 0 {
+		return nil
+	}
+	if sliceVal.Type().Elem().Kind() != reflect.Ptr {
+		return fmt.Errorf("meddler.InsertAll expects elements to be pointers to structs, found %T", src)
+	}
+
+	// records only keep the values set here once they have been inserted
+	var restores []func()
+	inserted := 0
+	defer func() {
+		for _, restore := range restores[inserted:] {
+			restore()
+		}
+	}()
+	for i := 0; i < sliceVal.Len(); i++ {
+		if err := beforeInsert(ctx, db, sliceVal.Index(i).Interface()); err != nil {
+			return err
+		}
+		restore, err := d.saveWritten(sliceVal.Index(i).Interface())
+		if err != nil {
+			return err
+		}
+		restores = append(restores, restore)
+		if err := d.initVersion(sliceVal.Index(i).Interface()); err != nil {
+			return err
+		}
+		if _, err := d.setTimestamps(sliceVal.Index(i).Interface(), true); err != nil {
+			return err
+		}
+	}
+
+	// all records must agree on whether the database generates their keys
+	var generated bool
+	var pkName string
+	for i := 0; i < sliceVal.Len(); i++ {
+		pkNames, pkFields, err := d.primaryKeyFields(sliceVal.Index(i).Interface())
+		if err != nil {
+			return err
+		}
+		zero := len(pkNames) == 1 && pkFields[0].IsZero()
+		if i == 0 {
+			generated = zero
+			if generated {
+				pkName = pkNames[0]
+			}
+		} else if zero != generated {
+			return fmt.Errorf("meddler.InsertAll: primary key must be zero in all records or non-zero in all records")
+		}
+	}
+	includePk := !generated
+	returning := generated && d.idStrategy() == IDReturning
+
+	names, err := d.ColumnsQuoted(sliceVal.Index(0).Interface(), includePk)
+	if err != nil {
+		return err
+	}
+	columns, err := d.Columns(sliceVal.Index(0).Interface(), includePk)
+	if err != nil {
+		return err
+	}
+	if len(columns) == 0 {
+		return fmt.Errorf("meddler.InsertAll: no columns to insert")
+	}
+
+	// find how many records fit in a single query
+	perQuery := sliceVal.Len()
+	if d.MaxPlaceholders > 0 {
+		perQuery = d.MaxPlaceholders / len(columns)
+		if perQuery == 0 {
+			return fmt.Errorf("meddler.InsertAll: %d columns exceed the limit of %d placeholders", len(columns), d.MaxPlaceholders)
+		}
+	}
+	if d.MaxInsertRows > 0 && perQuery > d.MaxInsertRows {
+		perQuery = d.MaxInsertRows
+	}
+
+	for start := 0; start < sliceVal.Len(); start += perQuery {
+		end := start + perQuery
+		if end > sliceVal.Len() {
+			end = sliceVal.Len()
+		}
+
+		// gather the query parts
+		var rows []string
+		var values []interface{}
+		for i := start; i < end; i++ {
+			rowValues, err := d.Values(sliceVal.Index(i).Interface(), includePk)
+			if err != nil {
+				return err
+			}
+			var placeholders []string
+			for range rowValues {
+				placeholders = append(placeholders, d.placeholder(len(values)+len(placeholders)+1))
+			}
+			rows = append(rows, "("+strings.Join(placeholders, ",")+")")
+			values = append(values, rowValues...)
+		}
+
+		// run the query
+		q := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s", d.quoted(table), names, strings.Join(rows, ","))
+		if !returning {
+			if _, err := d.runExecContext(ctx, db, q, values...); err != nil {
+				return &dbErr{msg: "meddler.InsertAll: DB error in Exec", err: err}
+			}
+			inserted = end
+			continue
+		}
+
+		q += " RETURNING " + d.quoted(pkName)
+		result, err := d.runQueryContext(ctx, db, q, values...)
+		if err != nil {
+			return &dbErr{msg: "meddler.InsertAll: DB error in Query", err: err}
+		}
+		if err := d.scanReturnedKeys(result, sliceVal, start, end); err != nil {
+			return err
+		}
+		inserted = end
+	}
+
+	for i := 0; i < sliceVal.Len(); i++ {
+		elt := sliceVal.Index(i).Interface()
+		if err := d.takeSnapshot(elt, nil); err != nil {
+			return err
+		}
+		if err := afterInsert(ctx, db, elt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// InsertAll using the Default Database type
+func InsertAll(db DB, table string, src interface{}) error {
+	return Default.InsertAll(db, table, src)
+}
+
+// InsertAllContext using the Default Database type
+func InsertAllContext(ctx context.Context, db DBContext, table string, src interface{}) error {
+	return Default.InsertAllContext(ctx, db, table, src)
+}
+
+// scanReturnedKeys reads the primary keys returned by a multi-row insert
+// and saves them in the records start through end-1 of sliceVal, in order.
+func (d *Database) scanReturnedKeys(rows *sql.Rows, sliceVal reflect.Value, start, end int) error {
+	// make sure we always close rows
+	defer rows.Close()
+
+	i := start
+	for rows.Next() {
+		if i >= end {
+			return fmt.Errorf("meddler.InsertAll: database returned more than %d primary keys", end-start)
+		}
+		var newPk interface{}
+		if err := rows.Scan(&newPk); err != nil {
+			return &dbErr{msg: "meddler.InsertAll: DB error in Scan", err: err}
+		}
+		if err := d.SetPrimaryKey(sliceVal.Index(i).Interface(), newPk); err != nil {
+			return &opErr{op: "meddler.InsertAll", err: err}
+		}
+		i++
+	}
+	if err := rows.Err(); err != nil {
+		return &dbErr{msg: "meddler.InsertAll: DB error reading primary keys", err: err}
+	}
+	if i != end {
+		return fmt.Errorf("meddler.InsertAll: database returned %d primary keys for %d records", i-start, end-start)
+	}
+
+	return rows.Close()
+}
+
 // Update performs and UPDATE query for the given record.
-// The record must have an integer primary key field that is non-zero,
-// and it will be used to select the database row that gets updated.
+// The record must have a primary key that is non-zero, and it will be
+// used to select the database row that gets updated. All fields of a
+// composite primary key are matched.
+//
+// If the record has a field tagged version, the row must also still have
+// the version of the record, which is incremented by the update. If no row
+// matches, ErrStaleObject is returned and the record is not changed.
+//
+// Columns tagged updated are set to the current time, unless the update
+// fails, and columns tagged created are not written.
 func (d *Database) Update(db DB, table string, src interface{}) error {
-	// gather the query parts
+	return d.UpdateContext(context.Background(), withContext(db), table, src)
+}
+
+// UpdateContext is like Update, but runs the query with the given context.
+func (d *Database) UpdateContext(ctx context.Context, db DBContext, table string, src interface{}) error {
+	if err := beforeUpdate(ctx, db, src); err != nil {
+		return err
+	}
+
 	names, err := d.Columns(src, false)
 	if err != nil {
 		return err
 	}
-	placeholders, err := d.Placeholders(src, false)
+
+	return d.update(ctx, db, "meddler.Update", table, src, names)
+}
+
+// update performs an UPDATE query writing the named columns of the record,
+// selecting the row by its primary key, and runs the AfterUpdate hook.
+// Callers run the BeforeUpdate hook before choosing the columns.
+func (d *Database) update(ctx context.Context, db DBContext, op, table string, src interface{}, names []string) error {
+	// the version column is written by update itself
+	versionName, version, err := d.versionField(src)
 	if err != nil {
 		return err
 	}
-	values, err := d.Values(src, false)
+	if versionName != "" {
+		names = withoutColumn(names, versionName)
+	}
+
+	// created columns keep the time of the insert, and updated columns
+	// are written with the current time
+	created, err := d.createdColumns(src)
+	if err != nil {
+		return err
+	}
+	for _, name := range created {
+		names = withoutColumn(names, name)
+	}
+
+	pkNames, pkFields, err := d.primaryKeyFields(src)
+	if err != nil {
+		return err
+	}
+	if len(pkNames) == 0 {
+		return &opErr{op: op, err: ErrNoPrimaryKey}
+	}
+	if pkIsZero(pkFields) {
+		return &opErr{op: op, err: ErrZeroPK}
+	}
+
+	// the record only keeps the new updated times if the update succeeds
+	restore, err := d.saveWritten(src)
+	if err != nil {
+		return err
+	}
+	done := false
+	defer func() {
+		if !done {
+			restore()
+		}
+	}()
+	updated, err := d.setTimestamps(src, false)
+	if err != nil {
+		return err
+	}
+	for _, name := range updated {
+		if !containsColumn(names, name) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("%s: no columns to update outside of the primary key", op)
+	}
+
+	// gather the query parts
+	values, err := d.SomeValues(src, names)
 	if err != nil {
 		return err
 	}
 
 	// form the column=placeholder pairs
 	var pairs []string
-	for i := 0; i < len(names) && i < len(placeholders); i++ {
-		pair := fmt.Sprintf("%s=%s", d.quoted(names[i]), placeholders[i])
+	for i, name := range names {
+		pair := fmt.Sprintf("%s=%s", d.quoted(name), d.placeholder(i+1))
 		pairs = append(pairs, pair)
 	}
 
-	pkName, pkValue, err := d.PrimaryKey(src)
-	if err != nil {
-		return err
+	// bump the version, and only match the row if it has not been bumped
+	// by someone else already
+	var oldVersion int64
+	if versionName != "" {
+		oldVersion = integerValue(version)
+		pairs = append(pairs, fmt.Sprintf("%s=%s", d.quoted(versionName), d.placeholder(len(pairs)+1)))
+		values = append(values, oldVersion+1)
 	}
-	if pkName == "" {
-		return fmt.Errorf("meddler.Update: no primary key field")
+	where := d.pkWhere(pkNames, len(pairs)+1)
+	for _, field := range pkFields {
+		values = append(values, pkValue(field))
 	}
-	if pkValue < 1 {
-		return fmt.Errorf("meddler.Update: primary key must be an integer > 0")
+	if versionName != "" {
+		where += fmt.Sprintf(" AND %s=%s", d.quoted(versionName), d.placeholder(len(values)+1))
+		values = append(values, oldVersion)
 	}
-	ph := d.placeholder(len(placeholders) + 1)
 
 	// run the query
-	q := fmt.Sprintf("UPDATE %s SET %s WHERE %s=%s", d.quoted(table),
-		strings.Join(pairs, ","),
-		d.quoted(pkName), ph)
-	values = append(values, pkValue)
+	q := fmt.Sprintf("UPDATE %s SET %s WHERE %s", d.quoted(table), strings.Join(pairs, ","), where)
+	result, err := d.runExecContext(ctx, db, q, values...)
+	if err != nil {
+		return &dbErr{msg: op + ": DB error in Exec", err: err}
+	}
 
-	if _, err := d.runExec(db, q, values...); err != nil {
-		return &dbErr{msg: "meddler.Update: DB error in Exec", err: err}
+	if versionName != "" {
+		count, err := result.RowsAffected()
+		if err != nil {
+			return &dbErr{msg: op + ": DB error getting rows affected", err: err}
+		}
+		if count == 0 {
+			return ErrStaleObject
+		}
+		setInteger(version, oldVersion+1)
+		names = append(names, versionName)
 	}
+	done = true
 
-	return nil
+	if err := d.takeSnapshot(src, names); err != nil {
+		return err
+	}
+	return afterUpdate(ctx, db, src)
 }
 
 // Update using the Default Database type
@@ -194,17 +633,136 @@ func Update(db DB, table string, src interface{}) error {
 	return Default.Update(db, table, src)
 }
 
+// UpdateContext using the Default Database type
+func UpdateContext(ctx context.Context, db DBContext, table string, src interface{}) error {
+	return Default.UpdateContext(ctx, db, table, src)
+}
+
+// UpdateColumns performs an UPDATE query for the given record that only
+// writes the named columns, leaving the rest of the row untouched. The
+// columns must belong to the struct and must not be primary key columns,
+// the version column, or columns tagged created.
+// The record's primary key selects the row, as with Update.
+func (d *Database) UpdateColumns(db DB, table string, src interface{}, columns ...string) error {
+	return d.UpdateColumnsContext(context.Background(), withContext(db), table, src, columns...)
+}
+
+// UpdateColumnsContext is like UpdateColumns, but runs the query with the given context.
+func (d *Database) UpdateColumnsContext(ctx context.Context, db DBContext, table string, src interface{}, columns ...string) error {
+	data, err := d.getFields(reflect.TypeOf(src))
+	if err != nil {
+		return err
+	}
+	if len(columns) == 0 {
+		return fmt.Errorf("meddler.UpdateColumns: no columns given")
+	}
+
+	// make sure every column maps to a field we may write
+	seen := make(map[string]bool)
+	for _, name := range columns {
+		field, present := data.fields[name]
+		if !present {
+			return &ColumnError{Column: name, Op: "meddler.UpdateColumns", Err: ErrUnmatchedColumn}
+		}
+		if field.primaryKey {
+			return &ColumnError{Column: name, Op: "meddler.UpdateColumns", Err: errors.New("part of the primary key")}
+		}
+		if field.version {
+			return &ColumnError{Column: name, Op: "meddler.UpdateColumns", Err: errors.New("the version column, which is bumped by every update")}
+		}
+		if field.created {
+			return &ColumnError{Column: name, Op: "meddler.UpdateColumns", Err: errors.New("tagged created, so only written by inserts")}
+		}
+		if seen[name] {
+			return &ColumnError{Column: name, Op: "meddler.UpdateColumns", Err: errors.New("given more than once")}
+		}
+		seen[name] = true
+	}
+
+	if err := beforeUpdate(ctx, db, src); err != nil {
+		return err
+	}
+	return d.update(ctx, db, "meddler.UpdateColumns", table, src, columns)
+}
+
+// UpdateColumns using the Default Database type
+func UpdateColumns(db DB, table string, src interface{}, columns ...string) error {
+	return Default.UpdateColumns(db, table, src, columns...)
+}
+
+// UpdateColumnsContext using the Default Database type
+func UpdateColumnsContext(ctx context.Context, db DBContext, table string, src interface{}, columns ...string) error {
+	return Default.UpdateColumnsContext(ctx, db, table, src, columns...)
+}
+
+// UpdateChanged performs an UPDATE query for the given record that only
+// writes the columns whose values differ from the snapshot taken when the
+// record was last loaded or saved. If nothing changed, no query is run.
+// The struct must embed a Snapshot; if no snapshot has been taken yet,
+// every column is written as with Update.
+func (d *Database) UpdateChanged(db DB, table string, src interface{}) error {
+	return d.UpdateChangedContext(context.Background(), withContext(db), table, src)
+}
+
+// UpdateChangedContext is like UpdateChanged, but runs the query with the given context.
+func (d *Database) UpdateChangedContext(ctx context.Context, db DBContext, table string, src interface{}) error {
+	if _, ok := src.(snapshotter); !ok {
+		return fmt.Errorf("meddler.UpdateChanged: %T does not embed meddler.Snapshot", src)
+	}
+	if err := beforeUpdate(ctx, db, src); err != nil {
+		return err
+	}
+
+	names, err := d.Columns(src, false)
+	if err != nil {
+		return err
+	}
+	if versionName, _, err := d.versionField(src); err != nil {
+		return err
+	} else if versionName != "" {
+		names = withoutColumn(names, versionName)
+	}
+	changed, ok, err := d.changedColumns(src, names)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		// nothing to compare against
+		changed = names
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	return d.update(ctx, db, "meddler.UpdateChanged", table, src, changed)
+}
+
+// UpdateChanged using the Default Database type
+func UpdateChanged(db DB, table string, src interface{}) error {
+	return Default.UpdateChanged(db, table, src)
+}
+
+// UpdateChangedContext using the Default Database type
+func UpdateChangedContext(ctx context.Context, db DBContext, table string, src interface{}) error {
+	return Default.UpdateChangedContext(ctx, db, table, src)
+}
+
 // Save performs an INSERT or an UPDATE, depending on whether or not
 // a primary keys exists and is non-zero.
 func (d *Database) Save(db DB, table string, src interface{}) error {
-	pkName, pkValue, err := d.PrimaryKey(src)
+	return d.SaveContext(context.Background(), withContext(db), table, src)
+}
+
+// SaveContext is like Save, but runs the queries with the given context.
+func (d *Database) SaveContext(ctx context.Context, db DBContext, table string, src interface{}) error {
+	pkNames, pkFields, err := d.primaryKeyFields(src)
 	if err != nil {
 		return err
 	}
-	if pkName != "" && pkValue != 0 {
-		return d.Update(db, table, src)
+	if len(pkNames) > 0 && !pkIsZero(pkFields) {
+		return d.UpdateContext(ctx, db, table, src)
 	} else {
-		return d.Insert(db, table, src)
+		return d.InsertContext(ctx, db, table, src)
 	}
 }
 
@@ -213,12 +771,403 @@ func Save(db DB, table string, src interface{}) error {
 	return Default.Save(db, table, src)
 }
 
+// SaveContext using the Default Database type
+func SaveContext(ctx context.Context, db DBContext, table string, src interface{}) error {
+	return Default.SaveContext(ctx, db, table, src)
+}
+
+// Upsert performs an INSERT query for the given record that turns into an
+// UPDATE of the existing row if one conflicts with it on conflictColumns,
+// which default to the primary key columns. The update writes every
+// inserted column except the conflict and primary key columns.
+// MySQL ignores conflictColumns and uses whichever unique key conflicts.
+//
+// Primary keys follow the same rules as Insert. A zero primary key
+// generated by the database is written back to the record when the
+// database can report it for both inserted and updated rows: through
+// RETURNING, or through LAST_INSERT_ID with MySQL.
+//
+// As the database decides whether the row is inserted or updated, only the
+// BeforeInsert and AfterInsert hooks are run. For the same reason, the
+// version column of an updated row is incremented in the database, but not
+// checked, and the version of the record is left as it was. Columns tagged
+// created keep their value in an updated row. If the upsert fails, the
+// version and times of the record keep their old values.
+func (d *Database) Upsert(db DB, table string, src interface{}, conflictColumns ...string) error {
+	return d.UpsertContext(context.Background(), withContext(db), table, src, conflictColumns...)
+}
+
+// UpsertContext is like Upsert, but runs the query with the given context.
+func (d *Database) UpsertContext(ctx context.Context, db DBContext, table string, src interface{}, conflictColumns ...string) error {
+	if d.UpsertSyntax == NoUpsert {
+		return fmt.Errorf("meddler.Upsert: not supported by this database")
+	}
+	if err := beforeInsert(ctx, db, src); err != nil {
+		return err
+	}
+	// the record only keeps the values set here if the upsert succeeds
+	restore, err := d.saveWritten(src)
+	if err != nil {
+		return err
+	}
+	done := false
+	defer func() {
+		if !done {
+			restore()
+		}
+	}()
+	if err := d.initVersion(src); err != nil {
+		return err
+	}
+	versionName, _, err := d.versionField(src)
+	if err != nil {
+		return err
+	}
+	if _, err := d.setTimestamps(src, true); err != nil {
+		return err
+	}
+	created, err := d.createdColumns(src)
+	if err != nil {
+		return err
+	}
+
+	pkNames, pkFields, err := d.primaryKeyFields(src)
+	if err != nil {
+		return err
+	}
+	if len(conflictColumns) == 0 {
+		conflictColumns = pkNames
+	}
+	if len(conflictColumns) == 0 && d.UpsertSyntax == OnConflict {
+		return &opErr{op: "meddler.Upsert", err: ErrNoPrimaryKey}
+	}
+
+	// as with Insert, a single zero primary key is generated by the database
+	generated := len(pkNames) == 1 && pkFields[0].IsZero()
+	includePk := !generated
+
+	// gather the query parts
+	names, err := d.Columns(src, includePk)
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("meddler.Upsert: no columns to insert")
+	}
+	namesPart, err := d.ColumnsQuoted(src, includePk)
+	if err != nil {
+		return err
+	}
+	valuesPart, err := d.PlaceholdersString(src, includePk)
+	if err != nil {
+		return err
+	}
+	values, err := d.Values(src, includePk)
+	if err != nil {
+		return err
+	}
+
+	// form the assignments for the update, skipping key columns
+	skip := make(map[string]bool)
+	for _, name := range conflictColumns {
+		skip[name] = true
+	}
+	for _, name := range pkNames {
+		skip[name] = true
+	}
+	for _, name := range created {
+		skip[name] = true
+	}
+	var pairs []string
+	for _, name := range names {
+		if skip[name] {
+			continue
+		}
+		if name == versionName {
+			// an existing row gets its own version bumped
+			if d.UpsertSyntax == OnDuplicateKey {
+				pairs = append(pairs, fmt.Sprintf("%s=%s+1", d.quoted(name), d.quoted(name)))
+			} else {
+				pairs = append(pairs, fmt.Sprintf("%s=%s.%s+1", d.quoted(name), d.quoted(table), d.quoted(name)))
+			}
+			continue
+		}
+		if d.UpsertSyntax == OnDuplicateKey {
+			pairs = append(pairs, fmt.Sprintf("%s=VALUES(%s)", d.quoted(name), d.quoted(name)))
+		} else {
+			pairs = append(pairs, fmt.Sprintf("%s=EXCLUDED.%s", d.quoted(name), d.quoted(name)))
+		}
+	}
+
+	q := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", d.quoted(table), namesPart, valuesPart)
+	returning := generated && d.idStrategy() == IDReturning
+	lastInsertID := false
+	switch d.UpsertSyntax {
+	case OnConflict:
+		var quotedConflict []string
+		for _, name := range conflictColumns {
+			quotedConflict = append(quotedConflict, d.quoted(name))
+		}
+		q += fmt.Sprintf(" ON CONFLICT (%s)", strings.Join(quotedConflict, ","))
+		if len(pairs) == 0 {
+			// RETURNING would report nothing for an existing row
+			q += " DO NOTHING"
+			returning = false
+		} else {
+			q += " DO UPDATE SET " + strings.Join(pairs, ",")
+		}
+
+	case OnDuplicateKey:
+		if generated {
+			// make LAST_INSERT_ID report the key of an updated row too
+			pk := d.quoted(pkNames[0])
+			pairs = append(pairs, fmt.Sprintf("%s=LAST_INSERT_ID(%s)", pk, pk))
+			lastInsertID = d.idStrategy() == IDLastInsertID
+		} else if len(pairs) == 0 {
+			// MySQL has no DO NOTHING, so assign a column to itself
+			pairs = append(pairs, fmt.Sprintf("%s=%s", d.quoted(names[0]), d.quoted(names[0])))
+		}
+		q += " ON DUPLICATE KEY UPDATE " + strings.Join(pairs, ",")
+	}
+
+	// run the query
+	switch {
+	case returning:
+		q += " RETURNING " + d.quoted(pkNames[0])
+		var newPk interface{}
+
+		if err := d.runQueryRowContext(ctx, db, &newPk, q, values...); err != nil {
+			return &dbErr{msg: "meddler.Upsert: DB error in QueryRow", err: err}
+		}
+		if err = d.SetPrimaryKey(src, newPk); err != nil {
+			return &opErr{op: "meddler.Upsert", err: err}
+		}
+
+	case lastInsertID:
+		result, err := d.runExecContext(ctx, db, q, values...)
+		if err != nil {
+			return &dbErr{msg: "meddler.Upsert: DB error in Exec", err: err}
+		}
+		newPk, err := result.LastInsertId()
+		if err != nil {
+			return &dbErr{msg: "meddler.Upsert: DB error getting new primary key value", err: err}
+		}
+		if err = d.SetPrimaryKey(src, newPk); err != nil {
+			return &opErr{op: "meddler.Upsert", err: err}
+		}
+
+	default:
+		if _, err := d.runExecContext(ctx, db, q, values...); err != nil {
+			return &dbErr{msg: "meddler.Upsert: DB error in Exec", err: err}
+		}
+	}
+	done = true
+
+	if err := d.takeSnapshot(src, nil); err != nil {
+		return err
+	}
+	return afterInsert(ctx, db, src)
+}
+
+// Upsert using the Default Database type
+func Upsert(db DB, table string, src interface{}, conflictColumns ...string) error {
+	return Default.Upsert(db, table, src, conflictColumns...)
+}
+
+// UpsertContext using the Default Database type
+func UpsertContext(ctx context.Context, db DBContext, table string, src interface{}, conflictColumns ...string) error {
+	return Default.UpsertContext(ctx, db, table, src, conflictColumns...)
+}
+
+// Delete performs a DELETE query for the given record.
+// The record must have a primary key that is non-zero, and it will be
+// used to select the database row that gets deleted.
+// Returns ErrNoRowsAffected if no row was deleted.
+func (d *Database) Delete(db DB, table string, src interface{}) error {
+	return d.DeleteContext(context.Background(), withContext(db), table, src)
+}
+
+// DeleteContext is like Delete, but runs the query with the given context.
+func (d *Database) DeleteContext(ctx context.Context, db DBContext, table string, src interface{}) error {
+	pkNames, pkFields, err := d.primaryKeyFields(src)
+	if err != nil {
+		return err
+	}
+	if len(pkNames) == 0 {
+		return &opErr{op: "meddler.Delete", err: ErrNoPrimaryKey}
+	}
+	if pkIsZero(pkFields) {
+		return &opErr{op: "meddler.Delete", err: ErrZeroPK}
+	}
+
+	var pk []interface{}
+	for _, field := range pkFields {
+		pk = append(pk, pkValue(field))
+	}
+	return d.deleteByPK(ctx, db, "meddler.Delete", table, pkNames, pk)
+}
+
+// Delete using the Default Database type
+func Delete(db DB, table string, src interface{}) error {
+	return Default.Delete(db, table, src)
+}
+
+// DeleteContext using the Default Database type
+func DeleteContext(ctx context.Context, db DBContext, table string, src interface{}) error {
+	return Default.DeleteContext(ctx, db, table, src)
+}
+
+// DeleteByPK performs a DELETE query for the row with the given primary key.
+// Only the type of src is used, to find the primary key columns, so it may
+// be a nil pointer to the struct type, e.g. (*Person)(nil). A composite
+// primary key needs one value per key field, in struct order.
+// Returns ErrNoRowsAffected if no row was deleted.
+func (d *Database) DeleteByPK(db DB, table string, src interface{}, pk ...interface{}) error {
+	return d.DeleteByPKContext(context.Background(), withContext(db), table, src, pk...)
+}
+
+// DeleteByPKContext is like DeleteByPK, but runs the query with the given context.
+func (d *Database) DeleteByPKContext(ctx context.Context, db DBContext, table string, src interface{}, pk ...interface{}) error {
+	data, err := d.getFields(reflect.TypeOf(src))
+	if err != nil {
+		return err
+	}
+	if len(data.pk) == 0 {
+		return &opErr{op: "meddler.DeleteByPK", err: ErrNoPrimaryKey}
+	}
+	if len(pk) != len(data.pk) {
+		return fmt.Errorf("meddler.DeleteByPK: primary key has %d fields, but %d values were given", len(data.pk), len(pk))
+	}
+
+	return d.deleteByPK(ctx, db, "meddler.DeleteByPK", table, data.pk, pkArgs(pk))
+}
+
+// DeleteByPK using the Default Database type
+func DeleteByPK(db DB, table string, src interface{}, pk ...interface{}) error {
+	return Default.DeleteByPK(db, table, src, pk...)
+}
+
+// DeleteByPKContext using the Default Database type
+func DeleteByPKContext(ctx context.Context, db DBContext, table string, src interface{}, pk ...interface{}) error {
+	return Default.DeleteByPKContext(ctx, db, table, src, pk...)
+}
+
+// SoftDelete marks the given record as deleted without removing its row,
+// by setting its column tagged softdelete to the current time. The record
+// must have a primary key that is non-zero, and a softdelete column, which
+// must allow NULL: rows where it is NULL are the ones not deleted. Load
+// skips rows that have been soft deleted.
+// Returns ErrNoRowsAffected if no row was marked, e.g. if it had already
+// been soft deleted.
+func (d *Database) SoftDelete(db DB, table string, src interface{}) error {
+	return d.SoftDeleteContext(context.Background(), withContext(db), table, src)
+}
+
+// SoftDeleteContext is like SoftDelete, but runs the query with the given context.
+func (d *Database) SoftDeleteContext(ctx context.Context, db DBContext, table string, src interface{}) error {
+	deleted, err := d.softDeleteColumn(src)
+	if err != nil {
+		return err
+	}
+	if deleted == "" {
+		return fmt.Errorf("meddler.SoftDelete: no softdelete field found")
+	}
+	pkNames, pkFields, err := d.primaryKeyFields(src)
+	if err != nil {
+		return err
+	}
+	if len(pkNames) == 0 {
+		return &opErr{op: "meddler.SoftDelete", err: ErrNoPrimaryKey}
+	}
+	if pkIsZero(pkFields) {
+		return &opErr{op: "meddler.SoftDelete", err: ErrZeroPK}
+	}
+
+	// write the current time through the meddler of the field
+	data, err := d.getFields(reflect.TypeOf(src))
+	if err != nil {
+		return err
+	}
+	field := fieldByIndex(reflect.ValueOf(src).Elem(), data.fields[deleted].index)
+	old := reflect.New(field.Type()).Elem()
+	old.Set(field)
+	now := d.now()
+	if field.Kind() == reflect.Ptr {
+		field.Set(reflect.ValueOf(&now))
+	} else {
+		field.Set(reflect.ValueOf(now))
+	}
+	values, err := d.SomeValues(src, []string{deleted})
+	if err != nil {
+		field.Set(old)
+		return err
+	}
+
+	// run the query
+	q := fmt.Sprintf("UPDATE %s SET %s=%s WHERE %s AND %s IS NULL", d.quoted(table),
+		d.quoted(deleted), d.placeholder(1),
+		d.pkWhere(pkNames, 2), d.quoted(deleted))
+	for _, field := range pkFields {
+		values = append(values, pkValue(field))
+	}
+
+	result, err := d.runExecContext(ctx, db, q, values...)
+	if err != nil {
+		field.Set(old)
+		return &dbErr{msg: "meddler.SoftDelete: DB error in Exec", err: err}
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		field.Set(old)
+		return &dbErr{msg: "meddler.SoftDelete: DB error getting rows affected", err: err}
+	}
+	if count == 0 {
+		field.Set(old)
+		return ErrNoRowsAffected
+	}
+
+	return d.takeSnapshot(src, []string{deleted})
+}
+
+// SoftDelete using the Default Database type
+func SoftDelete(db DB, table string, src interface{}) error {
+	return Default.SoftDelete(db, table, src)
+}
+
+// SoftDeleteContext using the Default Database type
+func SoftDeleteContext(ctx context.Context, db DBContext, table string, src interface{}) error {
+	return Default.SoftDeleteContext(ctx, db, table, src)
+}
+
+func (d *Database) deleteByPK(ctx context.Context, db DBContext, op, table string, pkNames []string, pk []interface{}) error {
+	q := fmt.Sprintf("DELETE FROM %s WHERE %s", d.quoted(table), d.pkWhere(pkNames, 1))
+
+	result, err := d.runExecContext(ctx, db, q, pk...)
+	if err != nil {
+		return &dbErr{msg: op + ": DB error in Exec", err: err}
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return &dbErr{msg: op + ": DB error getting rows affected", err: err}
+	}
+	if count == 0 {
+		return ErrNoRowsAffected
+	}
+
+	return nil
+}
+
 // QueryOne performs the given query with the given arguments, scanning a
 // single row of results into dst. Returns sql.ErrNoRows if there was no
 // result row.
 func (d *Database) QueryRow(db DB, dst interface{}, query string, args ...interface{}) error {
+	return d.QueryRowContext(context.Background(), withContext(db), dst, query, args...)
+}
+
+// QueryRowContext is like QueryRow, but runs the query with the given context.
+func (d *Database) QueryRowContext(ctx context.Context, db DBContext, dst interface{}, query string, args ...interface{}) error {
 	// perform the query
-	rows, err := d.runQuery(db, query, args...)
+	rows, err := d.runQueryContext(ctx, db, query, args...)
 	if err != nil {
 		return err
 	}
@@ -232,11 +1181,21 @@ func QueryRow(db DB, dst interface{}, query string, args ...interface{}) error {
 	return Default.QueryRow(db, dst, query, args...)
 }
 
+// QueryRowContext using the Default Database type
+func QueryRowContext(ctx context.Context, db DBContext, dst interface{}, query string, args ...interface{}) error {
+	return Default.QueryRowContext(ctx, db, dst, query, args...)
+}
+
 // QueryAll performs the given query with the given arguments, scanning
 // all results rows into dst.
 func (d *Database) QueryAll(db DB, dst interface{}, query string, args ...interface{}) error {
+	return d.QueryAllContext(context.Background(), withContext(db), dst, query, args...)
+}
+
+// QueryAllContext is like QueryAll, but runs the query with the given context.
+func (d *Database) QueryAllContext(ctx context.Context, db DBContext, dst interface{}, query string, args ...interface{}) error {
 	// perform the query
-	rows, err := d.runQuery(db, query, args...)
+	rows, err := d.runQueryContext(ctx, db, query, args...)
 	if err != nil {
 		return err
 	}
@@ -250,41 +1209,136 @@ func QueryAll(db DB, dst interface{}, query string, args ...interface{}) error {
 	return Default.QueryAll(db, dst, query, args...)
 }
 
-func (d *Database) runQuery(db DB, q string, args ...interface{}) (*sql.Rows, error) {
-	if d.StmtCacheFunc != nil {
-		stmt, err := d.StmtCacheFunc(db, q)
-		if err != nil {
-			return nil, err
-		}
-		if stmt != nil {
-			return stmt.Query(args...)
+// QueryAllContext using the Default Database type
+func QueryAllContext(ctx context.Context, db DBContext, dst interface{}, query string, args ...interface{}) error {
+	return Default.QueryAllContext(ctx, db, dst, query, args...)
+}
+
+// withoutColumn returns names without the given column.
+func withoutColumn(names []string, column string) []string {
+	var out []string
+	for _, name := range names {
+		if name != column {
+			out = append(out, name)
 		}
 	}
-	return db.Query(q, args...)
+	return out
 }
 
-func (d *Database) runQueryRow(db DB, q string, args ...interface{}) (*sql.Row, error) {
-	if d.StmtCacheFunc != nil {
-		stmt, err := d.StmtCacheFunc(db, q)
-		if err != nil {
-			return nil, err
+// containsColumn reports whether names includes the given column.
+func containsColumn(names []string, column string) bool {
+	for _, name := range names {
+		if name == column {
+			return true
 		}
-		if stmt != nil {
-			return stmt.QueryRow(args...), nil
+	}
+	return false
+}
+
+// pkArgs converts primary key values given by the caller into query
+// arguments, e.g. a [16]byte key into a []byte.
+func pkArgs(pk []interface{}) []interface{} {
+	args := make([]interface{}, len(pk))
+	for i, elt := range pk {
+		if elt != nil {
+			args[i] = pkValue(reflect.ValueOf(elt))
 		}
 	}
-	return db.QueryRow(q, args...), nil
+	return args
 }
 
-func (d *Database) runExec(db DB, q string, args ...interface{}) (sql.Result, error) {
-	if d.StmtCacheFunc != nil {
-		stmt, err := d.StmtCacheFunc(db, q)
-		if err != nil {
-			return nil, err
+// pkWhere forms the condition matching the given primary key columns,
+// e.g. "a"=$1 AND "b"=$2, with placeholders numbered starting at first.
+func (d *Database) pkWhere(pkNames []string, first int) string {
+	var parts []string
+	for i, name := range pkNames {
+		parts = append(parts, fmt.Sprintf("%s=%s", d.quoted(name), d.placeholder(first+i)))
+	}
+	return strings.Join(parts, " AND ")
+}
+
+// stmt returns the prepared statement to use for the query, or nil if the
+// query should be run directly on db. The release function must be called
+// once the statement has been started.
+func (d *Database) stmt(ctx context.Context, db DBContext, q string) (*sql.Stmt, func(), error) {
+	if d.StmtCache != nil {
+		return d.StmtCache.acquire(ctx, db, q)
+	}
+	var stmt *sql.Stmt
+	var err error
+	if d.StmtCacheContextFunc != nil {
+		stmt, err = d.StmtCacheContextFunc(ctx, db, q)
+	} else if d.StmtCacheFunc != nil {
+		// hand the original DB back to functions written without contexts
+		if nc, ok := db.(noContext); ok {
+			stmt, err = d.StmtCacheFunc(nc.DB, q)
+		} else if plain, ok := db.(DB); ok {
+			stmt, err = d.StmtCacheFunc(plain, q)
 		}
-		if stmt != nil {
-			return stmt.Exec(args...)
+	}
+	return stmt, func() {}, err
+}
+
+func (d *Database) runQueryContext(ctx context.Context, db DBContext, q string, args ...interface{}) (*sql.Rows, error) {
+	start := time.Now()
+	stmt, release, err := d.stmt(ctx, db, q)
+	if err != nil {
+		d.logQuery(ctx, q, args, start, -1, err)
+		return nil, err
+	}
+	defer release()
+	var rows *sql.Rows
+	if stmt != nil {
+		rows, err = stmt.QueryContext(ctx, args...)
+	} else {
+		rows, err = db.QueryContext(ctx, q, args...)
+	}
+	d.logQuery(ctx, q, args, start, -1, err)
+	return rows, err
+}
+
+// runQueryRowContext runs a query that returns a single row and scans the
+// row into dest. The error from scanning is the one logged, since a failing
+// query only reports its error when its row is scanned.
+func (d *Database) runQueryRowContext(ctx context.Context, db DBContext, dest interface{}, q string, args ...interface{}) error {
+	start := time.Now()
+	stmt, release, err := d.stmt(ctx, db, q)
+	if err != nil {
+		d.logQuery(ctx, q, args, start, -1, err)
+		return err
+	}
+	defer release()
+	var row *sql.Row
+	if stmt != nil {
+		row = stmt.QueryRowContext(ctx, args...)
+	} else {
+		row = db.QueryRowContext(ctx, q, args...)
+	}
+	err = row.Scan(dest)
+	d.logQuery(ctx, q, args, start, -1, err)
+	return err
+}
+
+func (d *Database) runExecContext(ctx context.Context, db DBContext, q string, args ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	stmt, release, err := d.stmt(ctx, db, q)
+	if err != nil {
+		d.logQuery(ctx, q, args, start, -1, err)
+		return nil, err
+	}
+	defer release()
+	var result sql.Result
+	if stmt != nil {
+		result, err = stmt.ExecContext(ctx, args...)
+	} else {
+		result, err = db.ExecContext(ctx, q, args...)
+	}
+	count := int64(-1)
+	if err == nil && d.QueryLogger != nil {
+		if n, err := result.RowsAffected(); err == nil {
+			count = n
 		}
 	}
-	return db.Exec(q, args...)
+	d.logQuery(ctx, q, args, start, count, err)
+	return result, err
 }
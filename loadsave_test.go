@@ -1,7 +1,12 @@
 package meddler
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 	"io"
+	"strings"
 	"testing"
 	"time"
 
@@ -39,6 +44,68 @@ func TestLoadUint(t *testing.T) {
 	db.Exec("delete from person")
 }
 
+func TestLoadContext(t *testing.T) {
+	once.Do(setup)
+	insertAliceBob(t)
+
+	elt := new(Person)
+	if err := LoadContext(context.Background(), db, "person", elt, 2); err != nil {
+		t.Errorf("LoadContext error on Bob: %v", err)
+		return
+	}
+	bob.ID = 2
+	personEqual(t, elt, bob)
+
+	// a cancelled context must stop the query
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := LoadContext(ctx, db, "person", new(Person), 2); err == nil {
+		t.Errorf("LoadContext with cancelled context: want error, got none")
+	}
+	db.Exec("delete from person")
+}
+
+func TestStmtCacheFuncContext(t *testing.T) {
+	once.Do(setup)
+	insertAliceBob(t)
+
+	var got DB
+	d := *SQLite
+	d.StmtCacheFunc = func(db DB, q string) (*sql.Stmt, error) {
+		got = db
+		return nil, nil
+	}
+
+	// StmtCacheFunc receives the DB handed to the non-context function
+	elt := new(Person)
+	if err := d.Load(db, "person", elt, 1); err != nil {
+		t.Errorf("Load error on Alice: %v", err)
+	}
+	if got != DB(db) {
+		t.Errorf("StmtCacheFunc: want %T %p, got %T %v", db, db, got, got)
+	}
+
+	// StmtCacheContextFunc takes precedence when set
+	var gotCtx context.Context
+	d.StmtCacheContextFunc = func(ctx context.Context, db DBContext, q string) (*sql.Stmt, error) {
+		gotCtx = ctx
+		return nil, nil
+	}
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, true)
+	got = nil
+	if err := d.InsertContext(ctx, db, "person", &Person{Name: "Carol", Opened: when}); err != nil {
+		t.Errorf("InsertContext error on Carol: %v", err)
+	}
+	if got != nil {
+		t.Errorf("StmtCacheFunc called when StmtCacheContextFunc is set")
+	}
+	if gotCtx == nil || gotCtx.Value(key{}) == nil {
+		t.Errorf("StmtCacheContextFunc did not receive the caller context")
+	}
+	db.Exec("delete from person")
+}
+
 func TestSave(t *testing.T) {
 	once.Do(setup)
 	insertAliceBob(t)
@@ -104,6 +171,792 @@ func TestSave(t *testing.T) {
 	db.Exec("delete from person")
 }
 
+func TestDelete(t *testing.T) {
+	once.Do(setup)
+	insertAliceBob(t)
+
+	if err := Delete(db, "person", alice); err != nil {
+		t.Errorf("Delete error on Alice: %v", err)
+	}
+	if err := Load(db, "person", new(Person), alice.ID); err != sql.ErrNoRows {
+		t.Errorf("Load after Delete: want sql.ErrNoRows, got %v", err)
+	}
+	if err := Delete(db, "person", alice); err != ErrNoRowsAffected {
+		t.Errorf("Delete of missing row: want ErrNoRowsAffected, got %v", err)
+	}
+
+	if err := DeleteByPK(db, "person", (*Person)(nil), bob.ID); err != nil {
+		t.Errorf("DeleteByPK error on Bob: %v", err)
+	}
+	if err := DeleteByPK(db, "person", (*Person)(nil), bob.ID); err != ErrNoRowsAffected {
+		t.Errorf("DeleteByPK of missing row: want ErrNoRowsAffected, got %v", err)
+	}
+
+	if err := Delete(db, "person", &Person{}); err == nil {
+		t.Errorf("Delete with zero primary key: want error, got none")
+	}
+	db.Exec("delete from person")
+}
+
+func TestStringPrimaryKey(t *testing.T) {
+	once.Do(setup)
+
+	// a non-zero key is provided by the caller
+	red := &Label{Code: "red", Title: "Red"}
+	if err := Insert(db, "label", red); err != nil {
+		t.Errorf("Insert error on red: %v", err)
+	}
+
+	elt := new(Label)
+	if err := Load(db, "label", elt, "red"); err != nil {
+		t.Errorf("Load error on red: %v", err)
+	}
+	if *elt != *red {
+		t.Errorf("Load: expected %v, found %v", red, elt)
+	}
+
+	elt.Title = "Crimson"
+	if err := Save(db, "label", elt); err != nil {
+		t.Errorf("Save error on red: %v", err)
+	}
+	if err := Load(db, "label", red, "red"); err != nil {
+		t.Errorf("Load error on red: %v", err)
+	}
+	if red.Title != "Crimson" {
+		t.Errorf("Save: expected title Crimson, found %s", red.Title)
+	}
+
+	// a zero key cannot be generated without RETURNING
+	if err := Insert(db, "label", &Label{Title: "Blank"}); err == nil {
+		t.Errorf("Insert with zero string key: want error, got none")
+	}
+	db.Exec("delete from label")
+}
+
+func TestByteArrayPrimaryKey(t *testing.T) {
+	once.Do(setup)
+
+	tok := &Token{ID: [16]byte{0xde, 0xad, 0xbe, 0xef}, Owner: "alice"}
+	if err := Insert(db, "token", tok); err != nil {
+		t.Errorf("Insert error on token: %v", err)
+	}
+
+	elt := new(Token)
+	if err := Load(db, "token", elt, tok.ID); err != nil {
+		t.Errorf("Load error on token: %v", err)
+	}
+	if *elt != *tok {
+		t.Errorf("Load: expected %v, found %v", tok, elt)
+	}
+
+	if err := Delete(db, "token", tok); err != nil {
+		t.Errorf("Delete error on token: %v", err)
+	}
+}
+
+func TestCompositePrimaryKey(t *testing.T) {
+	once.Do(setup)
+
+	m := &Membership{PersonID: 1, GroupID: 2, Role: "member"}
+	if err := Insert(db, "membership", m); err != nil {
+		t.Errorf("Insert error on membership: %v", err)
+	}
+	other := &Membership{PersonID: 1, GroupID: 3, Role: "member"}
+	if err := Insert(db, "membership", other); err != nil {
+		t.Errorf("Insert error on membership: %v", err)
+	}
+
+	m.Role = "admin"
+	if err := Update(db, "membership", m); err != nil {
+		t.Errorf("Update error on membership: %v", err)
+	}
+
+	elt := new(Membership)
+	if err := Load(db, "membership", elt, 1, 2); err != nil {
+		t.Errorf("Load error on membership: %v", err)
+	}
+	if *elt != *m {
+		t.Errorf("Load: expected %v, found %v", m, elt)
+	}
+	if err := Load(db, "membership", elt, 1, 3); err != nil {
+		t.Errorf("Load error on membership: %v", err)
+	}
+	if elt.Role != "member" {
+		t.Errorf("Update changed the wrong row: found role %s", elt.Role)
+	}
+	if err := Load(db, "membership", elt, 1); err == nil {
+		t.Errorf("Load with partial key: want error, got none")
+	}
+
+	if err := Delete(db, "membership", m); err != nil {
+		t.Errorf("Delete error on membership: %v", err)
+	}
+	if err := DeleteByPK(db, "membership", (*Membership)(nil), 1, 3); err != nil {
+		t.Errorf("DeleteByPK error on membership: %v", err)
+	}
+	if err := DeleteByPK(db, "membership", (*Membership)(nil), 1, 3); err != ErrNoRowsAffected {
+		t.Errorf("DeleteByPK of missing row: want ErrNoRowsAffected, got %v", err)
+	}
+}
+
+func TestEmbedded(t *testing.T) {
+	once.Do(setup)
+
+	a := &Article{
+		Title:      "Hello",
+		Timestamps: Timestamps{Created: when},
+		AuditInfo:  &AuditInfo{By: "alice", Note: "first"},
+	}
+	if err := Insert(db, "article", a); err != nil {
+		t.Errorf("Insert error on article: %v", err)
+	}
+
+	// nil embedded structs are written as null and allocated on load
+	b := &Article{Title: "World", Timestamps: Timestamps{Created: when}}
+	if err := Insert(db, "article", b); err != nil {
+		t.Errorf("Insert error on article: %v", err)
+	}
+
+	var lst []*Article
+	if err := QueryAll(db, &lst, "select * from article order by id"); err != nil {
+		t.Errorf("QueryAll error on articles: %v", err)
+		return
+	}
+	if len(lst) != 2 {
+		t.Errorf("QueryAll found %d rows, expected 2", len(lst))
+		return
+	}
+	if lst[0].Title != "Hello" || !lst[0].Created.Equal(when) || lst[0].AuditInfo == nil || lst[0].By != "alice" || lst[0].Note != "first" {
+		t.Errorf("Unexpected first article: %+v %+v", lst[0], lst[0].AuditInfo)
+	}
+	if lst[1].Title != "World" || !lst[1].Updated.IsZero() {
+		t.Errorf("Unexpected second article: %+v", lst[1])
+	}
+	db.Exec("delete from article")
+}
+
+func TestInsertAll(t *testing.T) {
+	once.Do(setup)
+
+	var people []*Person
+	for i := 0; i < 10; i++ {
+		people = append(people, &Person{Name: fmt.Sprintf("P%d", i), Email: "p@p.com", Opened: when})
+	}
+
+	// a tight placeholder limit forces several queries
+	d := *SQLite
+	d.MaxPlaceholders = 30
+	if err := d.InsertAll(db, "person", people); err != nil {
+		t.Errorf("InsertAll error: %v", err)
+	}
+
+	var lst []*Person
+	if err := QueryAll(db, &lst, "select * from person order by id"); err != nil {
+		t.Errorf("QueryAll error: %v", err)
+	}
+	if len(lst) != len(people) {
+		t.Errorf("InsertAll inserted %d rows, expected %d", len(lst), len(people))
+	}
+	for i, elt := range lst {
+		if elt.Name != people[i].Name {
+			t.Errorf("Row %d: expected name %s, found %s", i, people[i].Name, elt.Name)
+		}
+	}
+	db.Exec("delete from person")
+
+	// with RETURNING the generated keys are written back
+	d.UseReturningToGetID = true
+	if err := d.InsertAll(db, "person", &people); err != nil {
+		t.Errorf("InsertAll with RETURNING error: %v", err)
+	}
+	for i, elt := range people {
+		if elt.ID != int64(i+1) {
+			t.Errorf("Person %s ID is %d, expecting %d", elt.Name, elt.ID, i+1)
+		}
+	}
+	db.Exec("delete from person")
+
+	// keys must be all zero or all non-zero
+	mixed := []*Person{{Name: "A", Opened: when}, {ID: 5, Name: "B", Opened: when}}
+	if err := InsertAll(db, "person", mixed); err == nil {
+		t.Errorf("InsertAll with mixed keys: want error, got none")
+	}
+	d.MaxPlaceholders = 5
+	if err := d.InsertAll(db, "person", people); err == nil {
+		t.Errorf("InsertAll with too many columns: want error, got none")
+	}
+	if err := InsertAll(db, "person", people[0]); err == nil {
+		t.Errorf("InsertAll with non-slice: want error, got none")
+	}
+}
+
+func TestUpdateColumns(t *testing.T) {
+	once.Do(setup)
+	insertAliceBob(t)
+
+	// a stale copy must not overwrite the columns it does not name
+	stale := new(Person)
+	if err := Load(db, "person", stale, 1); err != nil {
+		t.Errorf("Load error on Alice: %v", err)
+	}
+	if _, err := db.Exec("update person set name = 'Alicia' where id = 1"); err != nil {
+		t.Errorf("DB error on update: %v", err)
+	}
+	stale.Email = "alice@example.com"
+	stale.Age = 33
+	if err := UpdateColumns(db, "person", stale, "Email", "Age"); err != nil {
+		t.Errorf("UpdateColumns error on Alice: %v", err)
+	}
+
+	elt := new(Person)
+	if err := Load(db, "person", elt, 1); err != nil {
+		t.Errorf("Load error on Alice: %v", err)
+	}
+	if elt.Name != "Alicia" || elt.Email != "alice@example.com" || elt.Age != 33 {
+		t.Errorf("Unexpected Alice after UpdateColumns: %+v", elt)
+	}
+
+	if err := UpdateColumns(db, "person", elt, "nickname"); err == nil {
+		t.Errorf("UpdateColumns with unknown column: want error, got none")
+	}
+	if err := UpdateColumns(db, "person", elt, "id"); err == nil {
+		t.Errorf("UpdateColumns with primary key column: want error, got none")
+	}
+	if err := UpdateColumns(db, "person", elt); err == nil {
+		t.Errorf("UpdateColumns with no columns: want error, got none")
+	}
+	db.Exec("delete from person")
+}
+
+type TrackedPerson struct {
+	Snapshot
+	ID     int64     `meddler:"id,pk"`
+	Name   string    `meddler:"name"`
+	Email  string    `meddler:"Email"`
+	Opened time.Time `meddler:"opened,utctime"`
+	Height *int      `meddler:"height"`
+}
+
+func TestUpdateChanged(t *testing.T) {
+	once.Do(setup)
+	insertAliceBob(t)
+
+	elt := new(TrackedPerson)
+	if err := Load(db, "person", elt, 1); err != nil {
+		t.Errorf("Load error on Alice: %v", err)
+	}
+
+	// with nothing changed, no query runs
+	rec := new(recordingDB)
+	if err := UpdateChanged(rec, "person", elt); err != nil {
+		t.Errorf("UpdateChanged error: %v", err)
+	}
+	if len(rec.queries) != 0 {
+		t.Errorf("UpdateChanged with no changes ran %q", rec.queries)
+	}
+
+	// changes through pointers are noticed too
+	elt.Email = "alice@example.com"
+	*elt.Height = 66
+	if err := SQLite.UpdateChanged(rec, "person", elt); err != nil {
+		t.Errorf("UpdateChanged error: %v", err)
+	}
+	expected := `UPDATE "person" SET "Email"=?,"height"=? WHERE "id"=?`
+	if len(rec.queries) != 1 || rec.queries[0] != expected {
+		t.Errorf("Expected query %q, found %q", expected, rec.queries)
+	}
+
+	// the recorded update refreshed the snapshot, so change it again
+	elt.Email = "alice@example.net"
+	if err := SQLite.UpdateChanged(db, "person", elt); err != nil {
+		t.Errorf("UpdateChanged error: %v", err)
+	}
+	rec = new(recordingDB)
+	if err := UpdateChanged(rec, "person", elt); err != nil {
+		t.Errorf("UpdateChanged error: %v", err)
+	}
+	if len(rec.queries) != 0 {
+		t.Errorf("UpdateChanged after saving ran %q", rec.queries)
+	}
+
+	// saving a copy of the struct leaves the snapshot of the original alone
+	copied := *elt
+	copied.Email = "alice@example.org"
+	if err := UpdateChanged(rec, "person", &copied); err != nil {
+		t.Errorf("UpdateChanged error on the copy: %v", err)
+	}
+	elt.Email = "alice@example.org"
+	rec = new(recordingDB)
+	if err := SQLite.UpdateChanged(rec, "person", elt); err != nil {
+		t.Errorf("UpdateChanged error: %v", err)
+	}
+	expected = `UPDATE "person" SET "Email"=? WHERE "id"=?`
+	if len(rec.queries) != 1 || rec.queries[0] != expected {
+		t.Errorf("Expected query %q after saving a copy, found %q", expected, rec.queries)
+	}
+	elt.Email = "alice@example.net"
+
+	reloaded := new(Person)
+	if err := Load(db, "person", reloaded, 1); err != nil {
+		t.Errorf("Load error on Alice: %v", err)
+	}
+	if reloaded.Email != "alice@example.net" || *reloaded.Height != 65 {
+		t.Errorf("Unexpected Alice after UpdateChanged: %+v", reloaded)
+	}
+
+	if err := UpdateChanged(db, "person", reloaded); err == nil {
+		t.Errorf("UpdateChanged without Snapshot: want error, got none")
+	}
+	db.Exec("delete from person")
+}
+
+// recordingDB is a DB that records queries instead of running them, for
+// checking the SQL generated for databases we cannot test against.
+type recordingDB struct {
+	queries []string
+	args    [][]interface{}
+}
+
+func (rec *recordingDB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	rec.queries = append(rec.queries, query)
+	rec.args = append(rec.args, args)
+	return recordedResult{}, nil
+}
+
+func (rec *recordingDB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	rec.queries = append(rec.queries, query)
+	rec.args = append(rec.args, args)
+	return nil, io.EOF
+}
+
+// QueryRow returns a row holding 42, the same key LastInsertId reports.
+func (rec *recordingDB) QueryRow(query string, args ...interface{}) *sql.Row {
+	rec.queries = append(rec.queries, query)
+	rec.args = append(rec.args, args)
+	once.Do(setup)
+	return db.QueryRow("select 42")
+}
+
+type recordedResult struct{}
+
+func (recordedResult) LastInsertId() (int64, error) { return 42, nil }
+func (recordedResult) RowsAffected() (int64, error) { return 1, nil }
+
+func TestUpsert(t *testing.T) {
+	once.Do(setup)
+
+	red := &Label{Code: "red", Title: "Red"}
+	if err := SQLite.Upsert(db, "label", red); err != nil {
+		t.Errorf("Upsert error inserting red: %v", err)
+	}
+	red.Title = "Crimson"
+	if err := SQLite.Upsert(db, "label", red); err != nil {
+		t.Errorf("Upsert error updating red: %v", err)
+	}
+
+	var lst []*Label
+	if err := QueryAll(db, &lst, "select * from label"); err != nil {
+		t.Errorf("QueryAll error: %v", err)
+	}
+	if len(lst) != 1 || lst[0].Title != "Crimson" {
+		t.Errorf("Expected a single label titled Crimson, found %v", lst)
+	}
+	db.Exec("delete from label")
+
+	// generated keys come back through RETURNING
+	d := *SQLite
+	d.UseReturningToGetID = true
+	p := &Person{Name: "Dave", Email: "dave@dave.com", Opened: when}
+	if err := d.Upsert(db, "person", p); err != nil {
+		t.Errorf("Upsert error inserting Dave: %v", err)
+	}
+	if p.ID == 0 {
+		t.Errorf("Upsert did not set the generated primary key")
+	}
+	db.Exec("delete from person")
+}
+
+func TestUpsertMySQL(t *testing.T) {
+	rec := new(recordingDB)
+	if err := MySQL.Upsert(rec, "label", &Label{Code: "red", Title: "Red"}); err != nil {
+		t.Errorf("Upsert error: %v", err)
+	}
+	expected := "INSERT INTO `label` (`code`,`title`) VALUES (?,?) ON DUPLICATE KEY UPDATE `title`=VALUES(`title`)"
+	if len(rec.queries) != 1 || rec.queries[0] != expected {
+		t.Errorf("Expected query %q, found %q", expected, rec.queries)
+	}
+
+	rec = new(recordingDB)
+	m := &Membership{PersonID: 1, GroupID: 2, Role: "member"}
+	if err := MySQL.Upsert(rec, "membership", m, "person_id", "group_id", "role"); err != nil {
+		t.Errorf("Upsert error: %v", err)
+	}
+	expected = "INSERT INTO `membership` (`person_id`,`group_id`,`role`) VALUES (?,?,?) ON DUPLICATE KEY UPDATE `person_id`=`person_id`"
+	if len(rec.queries) != 1 || rec.queries[0] != expected {
+		t.Errorf("Expected query %q, found %q", expected, rec.queries)
+	}
+
+	rec = new(recordingDB)
+	p := &Person{Name: "Erin", Opened: when}
+	if err := MySQL.Upsert(rec, "person", p, "name"); err != nil {
+		t.Errorf("Upsert error: %v", err)
+	}
+	if len(rec.queries) != 1 || !strings.HasSuffix(rec.queries[0], "`id`=LAST_INSERT_ID(`id`)") {
+		t.Errorf("Expected query to end with LAST_INSERT_ID, found %q", rec.queries)
+	}
+	if p.ID != 42 {
+		t.Errorf("Expected LastInsertId to set the ID to 42, found %d", p.ID)
+	}
+}
+
+type Document struct {
+	Snapshot
+	ID      int64  `meddler:"id,pk"`
+	Title   string `meddler:"title"`
+	Version int    `meddler:"version,version"`
+}
+
+func TestVersion(t *testing.T) {
+	once.Do(setup)
+
+	doc := &Document{Title: "Draft"}
+	if err := SQLite.Insert(db, "document", doc); err != nil {
+		t.Errorf("Insert error: %v", err)
+	}
+	if doc.Version != 1 {
+		t.Errorf("Expected Insert to set the version to 1, found %d", doc.Version)
+	}
+
+	// two workers load the same document
+	first, second := new(Document), new(Document)
+	if err := SQLite.Load(db, "document", first, doc.ID); err != nil {
+		t.Errorf("Load error: %v", err)
+	}
+	if err := SQLite.Load(db, "document", second, doc.ID); err != nil {
+		t.Errorf("Load error: %v", err)
+	}
+
+	first.Title = "First"
+	if err := SQLite.Update(db, "document", first); err != nil {
+		t.Errorf("Update error: %v", err)
+	}
+	if first.Version != 2 {
+		t.Errorf("Expected Update to bump the version to 2, found %d", first.Version)
+	}
+
+	// the second update would overwrite the first one
+	second.Title = "Second"
+	if err := SQLite.Update(db, "document", second); err != ErrStaleObject {
+		t.Errorf("Update of a stale document: want %v, got %v", ErrStaleObject, err)
+	}
+	if second.Version != 1 {
+		t.Errorf("Expected a failed Update to keep the version at 1, found %d", second.Version)
+	}
+	if err := SQLite.UpdateColumns(db, "document", second, "title"); err != ErrStaleObject {
+		t.Errorf("UpdateColumns of a stale document: want %v, got %v", ErrStaleObject, err)
+	}
+	var colErr *ColumnError
+	if err := SQLite.UpdateColumns(db, "document", first, "version"); !errors.As(err, &colErr) || colErr.Column != "version" {
+		t.Errorf("UpdateColumns of the version column: want a ColumnError, got %v", err)
+	}
+
+	first.Title = "First again"
+	if err := SQLite.UpdateChanged(db, "document", first); err != nil {
+		t.Errorf("UpdateChanged error: %v", err)
+	}
+	reloaded := new(Document)
+	if err := SQLite.Load(db, "document", reloaded, doc.ID); err != nil {
+		t.Errorf("Load error: %v", err)
+	}
+	if reloaded.Title != "First again" || reloaded.Version != 3 || first.Version != 3 {
+		t.Errorf("Expected the document at version 3, found %+v and %+v", reloaded, first)
+	}
+
+	rec := new(recordingDB)
+	if err := SQLite.Update(rec, "document", reloaded); err != nil {
+		t.Errorf("Update error: %v", err)
+	}
+	expected := `UPDATE "document" SET "title"=?,"version"=? WHERE "id"=? AND "version"=?`
+	if len(rec.queries) != 1 || rec.queries[0] != expected {
+		t.Errorf("Expected query %q, found %q", expected, rec.queries)
+	} else if args := rec.args[0]; args[1] != int64(4) || args[3] != int64(3) {
+		t.Errorf("Expected version arguments 4 and 3, found %v", args)
+	}
+
+	// an upsert of an existing row bumps its version in the database
+	if err := SQLite.Upsert(db, "document", &Document{ID: doc.ID, Title: "Upserted", Version: 1}); err != nil {
+		t.Errorf("Upsert error: %v", err)
+	}
+	if err := SQLite.Load(db, "document", reloaded, doc.ID); err != nil {
+		t.Errorf("Load error: %v", err)
+	}
+	if reloaded.Title != "Upserted" || reloaded.Version != 4 {
+		t.Errorf("Expected the upserted document at version 4, found %+v", reloaded)
+	}
+	db.Exec("delete from document")
+}
+
+type StampedDocument struct {
+	ID      int64      `meddler:"id,pk"`
+	Title   string     `meddler:"title"`
+	Version int        `meddler:"version,version"`
+	Created time.Time  `meddler:"created,utctime,created"`
+	Updated *time.Time `meddler:"updated,utctime,updated"`
+}
+
+func TestTimestamps(t *testing.T) {
+	once.Do(setup)
+
+	clock := when
+	d := *SQLite
+	d.Now = func() time.Time { return clock }
+
+	doc := &StampedDocument{Title: "Draft"}
+	if err := d.Insert(db, "document", doc); err != nil {
+		t.Errorf("Insert error: %v", err)
+	}
+	if !doc.Created.Equal(when) || doc.Updated == nil || !doc.Updated.Equal(when) {
+		t.Errorf("Expected Insert to set both times to %v, found %v and %v", when, doc.Created, doc.Updated)
+	}
+
+	// a created time set by the caller is kept
+	earlier := when.Add(-time.Hour)
+	imported := &StampedDocument{Title: "Imported", Created: earlier}
+	if err := d.Insert(db, "document", imported); err != nil {
+		t.Errorf("Insert error: %v", err)
+	}
+	if !imported.Created.Equal(earlier) || !imported.Updated.Equal(when) {
+		t.Errorf("Expected Insert to keep the created time, found %v and %v", imported.Created, imported.Updated)
+	}
+
+	// updates refresh the updated time, but never write the created time
+	clock = when.Add(time.Hour)
+	doc.Created = time.Time{}
+	doc.Title = "Final"
+	if err := d.Update(db, "document", doc); err != nil {
+		t.Errorf("Update error: %v", err)
+	}
+	if !doc.Updated.Equal(clock) {
+		t.Errorf("Expected Update to set the updated time to %v, found %v", clock, doc.Updated)
+	}
+	reloaded := new(StampedDocument)
+	if err := d.Load(db, "document", reloaded, doc.ID); err != nil {
+		t.Errorf("Load error: %v", err)
+	}
+	if !reloaded.Created.Equal(when) || !reloaded.Updated.Equal(clock) {
+		t.Errorf("Expected times %v and %v after Update, found %v and %v", when, clock, reloaded.Created, reloaded.Updated)
+	}
+
+	clock = when.Add(2 * time.Hour)
+	rec := new(recordingDB)
+	if err := d.UpdateColumns(rec, "document", reloaded, "title"); err != nil {
+		t.Errorf("UpdateColumns error: %v", err)
+	}
+	expected := `UPDATE "document" SET "title"=?,"updated"=?,"version"=? WHERE "id"=? AND "version"=?`
+	if len(rec.queries) != 1 || rec.queries[0] != expected {
+		t.Errorf("Expected query %q, found %q", expected, rec.queries)
+	}
+	var colErr *ColumnError
+	if err := d.UpdateColumns(rec, "document", reloaded, "created"); !errors.As(err, &colErr) || colErr.Column != "created" {
+		t.Errorf("UpdateColumns of a created column: want a ColumnError, got %v", err)
+	}
+
+	rec = new(recordingDB)
+	if err := d.Upsert(rec, "document", &StampedDocument{ID: 7, Title: "Upserted"}); err != nil {
+		t.Errorf("Upsert error: %v", err)
+	}
+	expected = `INSERT INTO "document" ("id","title","version","created","updated") VALUES (?,?,?,?,?) ON CONFLICT ("id") DO UPDATE SET "title"=EXCLUDED."title","version"="document"."version"+1,"updated"=EXCLUDED."updated"`
+	if len(rec.queries) != 1 || rec.queries[0] != expected {
+		t.Errorf("Expected query %q, found %q", expected, rec.queries)
+	}
+
+	// a failed update leaves the updated time alone
+	stale := new(StampedDocument)
+	if err := d.Load(db, "document", stale, doc.ID); err != nil {
+		t.Errorf("Load error: %v", err)
+	}
+	stale.Version--
+	before := *stale.Updated
+	clock = when.Add(3 * time.Hour)
+	if err := d.Update(db, "document", stale); err != ErrStaleObject {
+		t.Errorf("Update of a stale record: want %v, got %v", ErrStaleObject, err)
+	}
+	if !stale.Updated.Equal(before) {
+		t.Errorf("Expected a stale Update to keep the updated time %v, found %v", before, stale.Updated)
+	}
+	unsaved := &StampedDocument{Title: "Unsaved"}
+	if err := d.Update(db, "document", unsaved); err == nil {
+		t.Errorf("Update with a zero key: want error, got none")
+	}
+	if unsaved.Updated != nil {
+		t.Errorf("Expected a failed Update to leave the updated time nil, found %v", unsaved.Updated)
+	}
+
+	// and so do failed inserts, which leave the version alone too
+	if err := d.Insert(db, "nonesuch", unsaved); err == nil {
+		t.Errorf("Insert into a missing table: want error, got none")
+	}
+	if err := d.Upsert(db, "nonesuch", unsaved); err == nil {
+		t.Errorf("Upsert into a missing table: want error, got none")
+	}
+	batch := []*StampedDocument{unsaved, {Title: "Also unsaved"}}
+	if err := d.InsertAll(db, "nonesuch", batch); err == nil {
+		t.Errorf("InsertAll into a missing table: want error, got none")
+	}
+	for _, elt := range batch {
+		if elt.Version != 0 || !elt.Created.IsZero() || elt.Updated != nil {
+			t.Errorf("Expected failed inserts to leave the version and times unset, found %+v", elt)
+		}
+	}
+	db.Exec("delete from document")
+}
+
+type ArchivedDocument struct {
+	ID      int64      `meddler:"id,pk"`
+	Title   string     `meddler:"title"`
+	Version int        `meddler:"version"`
+	Deleted *time.Time `meddler:"deleted,utctime,softdelete"`
+}
+
+func TestSoftDelete(t *testing.T) {
+	once.Do(setup)
+
+	d := *SQLite
+	d.Now = func() time.Time { return when }
+
+	doc := &ArchivedDocument{Title: "Retired", Version: 1}
+	if err := d.Insert(db, "document", doc); err != nil {
+		t.Fatalf("Insert error: %v", err)
+	}
+	if err := d.SoftDelete(db, "document", doc); err != nil {
+		t.Errorf("SoftDelete error: %v", err)
+	}
+	if doc.Deleted == nil || !doc.Deleted.Equal(when) {
+		t.Errorf("Expected SoftDelete to set the deleted time to %v, found %v", when, doc.Deleted)
+	}
+	if err := d.SoftDelete(db, "document", doc); err != ErrNoRowsAffected {
+		t.Errorf("SoftDelete of a deleted row: want %v, got %v", ErrNoRowsAffected, err)
+	}
+
+	// the row is still there, but Load skips it
+	var count int
+	if err := db.QueryRow("select count(*) from document where id = ?", doc.ID).Scan(&count); err != nil || count != 1 {
+		t.Errorf("Expected the row to remain after SoftDelete, found %d rows (%v)", count, err)
+	}
+	if err := d.Load(db, "document", new(ArchivedDocument), doc.ID); err != sql.ErrNoRows {
+		t.Errorf("Load of a deleted row: want %v, got %v", sql.ErrNoRows, err)
+	}
+	reloaded := new(ArchivedDocument)
+	if err := d.LoadWithDeleted(db, "document", reloaded, doc.ID); err != nil {
+		t.Errorf("LoadWithDeleted error: %v", err)
+	}
+	if reloaded.Title != "Retired" || reloaded.Deleted == nil || !reloaded.Deleted.Equal(when) {
+		t.Errorf("Expected LoadWithDeleted to find the deleted row, found %+v", reloaded)
+	}
+
+	rec := new(recordingDB)
+	PostgreSQL.Load(rec, "document", new(ArchivedDocument), 1)
+	expected := `SELECT "id","title","version","deleted" FROM "document" WHERE "id"=$1 AND "deleted" IS NULL`
+	if len(rec.queries) != 1 || rec.queries[0] != expected {
+		t.Errorf("Expected query %q, found %q", expected, rec.queries)
+	}
+
+	if err := d.SoftDelete(db, "document", &StampedDocument{ID: doc.ID}); err == nil {
+		t.Errorf("SoftDelete without a softdelete field: want error, got none")
+	}
+
+	// a time.Time column must be written as NULL while not deleted
+	type PlainDeleted struct {
+		ID      int64     `meddler:"id,pk"`
+		Deleted time.Time `meddler:"deleted,softdelete"`
+	}
+	if _, err := d.Columns(&PlainDeleted{}, true); err == nil {
+		t.Errorf("softdelete on a time.Time with the identity meddler: want error, got none")
+	}
+	type ZeroDeleted struct {
+		ID      int64     `meddler:"id,pk"`
+		Title   string    `meddler:"title"`
+		Version int       `meddler:"version"`
+		Deleted time.Time `meddler:"deleted,utctimez,softdelete"`
+	}
+	zero := &ZeroDeleted{Title: "Zero", Version: 1}
+	if err := d.Insert(db, "document", zero); err != nil {
+		t.Errorf("Insert error: %v", err)
+	}
+	if err := d.Load(db, "document", new(ZeroDeleted), zero.ID); err != nil {
+		t.Errorf("Load of a row that is not deleted: %v", err)
+	}
+	db.Exec("delete from document")
+}
+
+func TestSQLServer(t *testing.T) {
+	rec := new(recordingDB)
+	p := &Person{Name: "Carol", Email: "carol@carol.com", Opened: when}
+	if err := SQLServer.Insert(rec, "person", p); err != nil {
+		t.Errorf("Insert error: %v", err)
+	}
+	expected := "INSERT INTO [person] ([name],[Email],[Age],[opened],[closed],[updated],[height]) OUTPUT INSERTED.[id] VALUES (@p1,@p2,@p3,@p4,@p5,@p6,@p7)"
+	if len(rec.queries) != 1 || rec.queries[0] != expected {
+		t.Errorf("Expected query %q, found %q", expected, rec.queries)
+	}
+	if p.ID != 42 {
+		t.Errorf("Expected OUTPUT INSERTED to set the ID to 42, found %d", p.ID)
+	}
+
+	rec = new(recordingDB)
+	if err := SQLServer.UpdateColumns(rec, "person", p, "Email"); err != nil {
+		t.Errorf("UpdateColumns error: %v", err)
+	}
+	expected = "UPDATE [person] SET [Email]=@p1 WHERE [id]=@p2"
+	if len(rec.queries) != 1 || rec.queries[0] != expected {
+		t.Errorf("Expected query %q, found %q", expected, rec.queries)
+	}
+
+	// a VALUES list holds at most 1000 rows
+	var labels []*Label
+	for i := 0; i < 1001; i++ {
+		labels = append(labels, &Label{Code: fmt.Sprintf("label%d", i)})
+	}
+	rec = new(recordingDB)
+	if err := SQLServer.InsertAll(rec, "label", labels); err != nil {
+		t.Errorf("InsertAll error: %v", err)
+	}
+	if len(rec.queries) != 2 || len(rec.args[0]) != 2000 || len(rec.args[1]) != 2 {
+		t.Errorf("Expected InsertAll to split 1001 labels into 1000 and 1, found %d queries", len(rec.queries))
+	}
+}
+
+func TestOracle(t *testing.T) {
+	rec := new(recordingDB)
+	p := &Person{Name: "Carol", Email: "carol@carol.com", Opened: when}
+	if err := Oracle.Insert(rec, "person", p); err != nil {
+		t.Errorf("Insert error: %v", err)
+	}
+	expected := `INSERT INTO "person" ("name","Email","Age","opened","closed","updated","height") VALUES (:1,:2,:3,:4,:5,:6,:7) RETURNING "id" INTO :8`
+	if len(rec.queries) != 1 || rec.queries[0] != expected {
+		t.Errorf("Expected query %q, found %q", expected, rec.queries)
+	} else if out, ok := rec.args[0][7].(sql.Out); !ok {
+		t.Errorf("Expected the last argument to be an sql.Out, found %T", rec.args[0][7])
+	} else if _, ok := out.Dest.(*int64); !ok {
+		t.Errorf("Expected an *int64 destination for the key, found %T", out.Dest)
+	}
+
+	// no multi-row VALUES lists
+	rec = new(recordingDB)
+	labels := []*Label{{Code: "red"}, {Code: "green"}}
+	if err := Oracle.InsertAll(rec, "label", labels); err != nil {
+		t.Errorf("InsertAll error: %v", err)
+	}
+	expected = `INSERT INTO "label" ("code","title") VALUES (:1,:2)`
+	if len(rec.queries) != 2 || rec.queries[0] != expected {
+		t.Errorf("Expected two queries %q, found %q", expected, rec.queries)
+	}
+
+	if err := Oracle.Upsert(rec, "label", labels[0]); err == nil {
+		t.Errorf("Upsert with Oracle: want error, got none")
+	}
+}
+
 func TestDriverErr(t *testing.T) {
 	err, ok := DriverErr(io.EOF)
 	if ok {
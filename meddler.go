@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"sync"
 	"time"
 )
 
@@ -31,15 +32,69 @@ type Meddler interface {
 
 // Register sets up a meddler type. Meddlers get a chance to meddle with the
 // data being loaded or saved when a field is annotated with the name of the meddler.
-// The registry is global.
+// The registry is global; see Registry for meddlers used by a single Database.
 func Register(name string, m Meddler) {
-	if name == "pk" {
-		panic("meddler.Register: pk cannot be used as a meddler name")
+	registry.Register(name, m)
+}
+
+// Registry is a set of named meddlers. Setting the Registry field of a
+// Database makes its meddlers available to that Database only, taking
+// precedence over the global ones with the same name. A Registry is safe
+// for concurrent use.
+type Registry struct {
+	mu       sync.RWMutex
+	meddlers map[string]Meddler
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{meddlers: make(map[string]Meddler)}
+}
+
+// Register adds a meddler to the registry under the given name, replacing
+// the meddler previously registered with that name, if any.
+func (r *Registry) Register(name string, m Meddler) {
+	if tagOptions[name] {
+		panic("meddler.Register: " + name + " cannot be used as a meddler name")
 	}
-	registry[name] = m
+
+	r.mu.Lock()
+	r.meddlers[name] = m
+	r.mu.Unlock()
+
+	// struct fields may have been resolved with the previous meddler
+	resetFieldsCache()
+}
+
+func (r *Registry) lookup(name string) (Meddler, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	m, present := r.meddlers[name]
+	return m, present
 }
 
-var registry = make(map[string]Meddler)
+var registry = NewRegistry()
+
+// tagOptions are the struct tag options that are not meddlers.
+var tagOptions = map[string]bool{
+	"pk":         true,
+	"version":    true,
+	"created":    true,
+	"updated":    true,
+	"softdelete": true,
+}
+
+// meddler returns the meddler registered with the given name, looking in
+// the registry of d before the global one.
+func (d *Database) meddler(name string) (Meddler, bool) {
+	if d.Registry != nil {
+		if m, present := d.Registry.lookup(name); present {
+			return m, true
+		}
+	}
+	return registry.lookup(name)
+}
 
 func init() {
 	Register("identity", IdentityMeddler(false))
@@ -70,6 +125,37 @@ func (elt IdentityMeddler) PreWrite(field interface{}) (saveValue interface{}, e
 	return field, nil
 }
 
+// byteArrayMeddler is the default meddler for byte array fields such as
+// [16]byte, which database drivers cannot handle directly. Values are
+// written as byte slices, and read back from byte slices or UUID strings.
+type byteArrayMeddler struct{}
+
+func (elt byteArrayMeddler) PreRead(fieldAddr interface{}) (scanTarget interface{}, err error) {
+	return new([]byte), nil
+}
+
+func (elt byteArrayMeddler) PostRead(fieldAddr, scanTarget interface{}) error {
+	raw := *scanTarget.(*[]byte)
+	field := reflect.ValueOf(fieldAddr).Elem()
+	if raw == nil {
+		// null column, so set target to be zero value
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+	if len(raw) != field.Len() {
+		raw = parseUUID(raw)
+	}
+	if len(raw) != field.Len() {
+		return fmt.Errorf("byteArrayMeddler.PostRead: cannot store %d bytes in %v", len(raw), field.Type())
+	}
+	reflect.Copy(field, reflect.ValueOf(raw))
+	return nil
+}
+
+func (elt byteArrayMeddler) PreWrite(field interface{}) (saveValue interface{}, err error) {
+	return pkValue(reflect.ValueOf(field)), nil
+}
+
 // TimeMeddler provides useful operations on time.Time fields. It can convert the zero time
 // to and from a null column, and it can convert the time zone to UTC on save and to Local on load.
 type TimeMeddler struct {
@@ -89,6 +175,9 @@ func (elt TimeMeddler) PreRead(fieldAddr interface{}) (scanTarget interface{}, e
 			return nil, fmt.Errorf("meddler.TimeMeddler cannot be used on a *time.Time field, only time.Time")
 		}
 		return fieldAddr, nil
+	case *interface{}:
+		// a map entry, see ScanOptions
+		return new(*time.Time), nil
 	default:
 		return nil, fmt.Errorf("meddler.TimeMeddler.PreRead: unknown struct field type: %T", fieldAddr)
 	}
@@ -135,6 +224,22 @@ func (elt TimeMeddler) PostRead(fieldAddr, scanTarget interface{}) error {
 
 		return nil
 
+	case *interface{}:
+		src := scanTarget.(**time.Time)
+		if *src == nil {
+			if elt.ZeroIsNull {
+				*tgt = time.Time{}
+			} else {
+				*tgt = nil
+			}
+		} else if elt.Local {
+			*tgt = (*src).Local()
+		} else {
+			*tgt = (*src).UTC()
+		}
+
+		return nil
+
 	default:
 		return fmt.Errorf("meddler.TimeMeddler.PostRead: unknown struct field type: %T", fieldAddr)
 	}
@@ -1,6 +1,10 @@
 package meddler
 
 import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -111,3 +115,79 @@ func TestGobMeddler(t *testing.T) {
 		t.Errorf("error wiping item table: %v", err)
 	}
 }
+
+// upperMeddler stores strings in upper case.
+type upperMeddler struct {
+	IdentityMeddler
+}
+
+func (upperMeddler) PreWrite(field interface{}) (interface{}, error) {
+	return strings.ToUpper(field.(string)), nil
+}
+
+type Shouter struct {
+	ID   int64  `meddler:"id,pk"`
+	Name string `meddler:"name,upper"`
+}
+
+func TestRegistry(t *testing.T) {
+	d := *SQLite
+	d.Registry = NewRegistry()
+	d.Registry.Register("upper", upperMeddler{})
+	d.Registry.Register("json", GobMeddler(false))
+
+	// meddlers of a Database registry are only seen by that Database
+	values, err := d.Values(&Shouter{Name: "bob"}, true)
+	if err != nil {
+		t.Errorf("Values error: %v", err)
+	} else if values[1] != "BOB" {
+		t.Errorf("Expected the upper meddler to give BOB, found %v", values[1])
+	}
+	if _, err := SQLite.Values(&Shouter{Name: "bob"}, true); err == nil {
+		t.Errorf("Values with an unregistered meddler: want error, got none")
+	}
+
+	// and take precedence over global meddlers of the same name
+	data, err := d.getFields(reflect.TypeOf((*ItemJson)(nil)))
+	if err != nil {
+		t.Fatalf("Error in getFields: %v", err)
+	}
+	if data.fields["stuff"].meddler != GobMeddler(false) {
+		t.Errorf("Expected the Database registry to override json")
+	}
+	if data.fields["stuffz"].meddler != JSONMeddler(true) {
+		t.Errorf("Expected jsongzip to come from the global registry")
+	}
+	data, err = SQLite.getFields(reflect.TypeOf((*ItemJson)(nil)))
+	if err != nil {
+		t.Fatalf("Error in getFields: %v", err)
+	}
+	if data.fields["stuff"].meddler != JSONMeddler(false) {
+		t.Errorf("Expected the global json meddler without a Database registry")
+	}
+}
+
+func TestRegistryConcurrent(t *testing.T) {
+	d := *SQLite
+	d.Registry = NewRegistry()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			d.Registry.Register(fmt.Sprintf("upper%d", i), upperMeddler{})
+		}(i)
+		go func() {
+			defer wg.Done()
+			if _, err := d.Columns(&ItemJson{}, true); err != nil {
+				t.Errorf("Columns error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if _, present := d.Registry.lookup("upper9"); !present {
+		t.Errorf("Expected upper9 to be registered")
+	}
+}
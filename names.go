@@ -0,0 +1,111 @@
+package meddler
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// SnakeCase maps a Go name to snake_case, keeping initialisms together,
+// e.g. "UserID" to "user_id" and "HTTPServer" to "http_server".
+func SnakeCase(name string) string {
+	runes := []rune(name)
+	var out []rune
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || unicode.IsUpper(prev) && nextLower {
+				out = append(out, '_')
+			}
+		}
+		out = append(out, unicode.ToLower(r))
+	}
+	return string(out)
+}
+
+// LowerCase maps a Go name to lower case, e.g. "UserID" to "userid".
+func LowerCase(name string) string {
+	return strings.ToLower(name)
+}
+
+// CamelCase maps a Go name to camelCase by lowering its leading capitals,
+// e.g. "UserID" to "userID" and "HTTPServer" to "httpServer".
+func CamelCase(name string) string {
+	runes := []rune(name)
+	for i := 0; i < len(runes) && unicode.IsUpper(runes[i]); i++ {
+		// keep the capital that starts the next word
+		if i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+			break
+		}
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
+
+// mapName applies NameMapper to a Go name, for fields without a column name
+// in their tag and for table names.
+func (d *Database) mapName(name string) string {
+	if d.NameMapper == nil {
+		return name
+	}
+	return d.NameMapper(name)
+}
+
+// nameMapperID identifies the NameMapper in the key of the reflection cache,
+// as functions cannot be compared. The mappers provided here are told apart
+// by their code pointer, but closures made from the same function literal
+// share one whatever they capture, so other mappers are identified by
+// NameMapperKey, or failing that by the Database itself.
+func (d *Database) nameMapperID() interface{} {
+	if d.NameMapper == nil {
+		return nil
+	}
+	if d.NameMapperKey != "" {
+		return d.NameMapperKey
+	}
+	id := reflect.ValueOf(d.NameMapper).Pointer()
+	for _, builtin := range []func(string) string{SnakeCase, LowerCase, CamelCase} {
+		if id == reflect.ValueOf(builtin).Pointer() {
+			return id
+		}
+	}
+	return d
+}
+
+// TableNamer is implemented by structs that know the name of their table.
+type TableNamer interface {
+	TableName() string
+}
+
+// TableName returns the name of the table for src, which may be a struct,
+// a pointer to one, or a slice of either. It is the result of the
+// TableName method if the struct implements TableNamer, and otherwise the
+// name of the struct type passed through NameMapper, e.g. "person" for a
+// Person struct with SnakeCase.
+func (d *Database) TableName(src interface{}) (string, error) {
+	if namer, ok := src.(TableNamer); ok {
+		return namer.TableName(), nil
+	}
+
+	t := reflect.TypeOf(src)
+	for t != nil && (t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice) {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return "", fmt.Errorf("meddler.TableName: %T is not a struct", src)
+	}
+
+	// the method may be declared on either the struct or its pointer
+	if namer, ok := reflect.New(t).Interface().(TableNamer); ok {
+		return namer.TableName(), nil
+	}
+
+	return d.mapName(t.Name()), nil
+}
+
+// TableName using the Default Database type
+func TableName(src interface{}) (string, error) {
+	return Default.TableName(src)
+}
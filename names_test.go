@@ -0,0 +1,175 @@
+package meddler
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNameMappers(t *testing.T) {
+	tests := []struct {
+		name, snake, lower, camel string
+	}{
+		{"Email", "email", "email", "email"},
+		{"ID", "id", "id", "id"},
+		{"UserID", "user_id", "userid", "userID"},
+		{"HTTPServer", "http_server", "httpserver", "httpServer"},
+		{"Address2", "address2", "address2", "address2"},
+		{"V2Name", "v2_name", "v2name", "v2Name"},
+		{"createdAt", "created_at", "createdat", "createdAt"},
+	}
+	for _, test := range tests {
+		if got := SnakeCase(test.name); got != test.snake {
+			t.Errorf("SnakeCase(%q): want %q, got %q", test.name, test.snake, got)
+		}
+		if got := LowerCase(test.name); got != test.lower {
+			t.Errorf("LowerCase(%q): want %q, got %q", test.name, test.lower, got)
+		}
+		if got := CamelCase(test.name); got != test.camel {
+			t.Errorf("CamelCase(%q): want %q, got %q", test.name, test.camel, got)
+		}
+	}
+}
+
+type MappedPerson struct {
+	ID        int64  `meddler:"id,pk"`
+	FirstName string `meddler:",zeroisnull"`
+	HomeEmail string
+	Misc      string `meddler:"other"`
+}
+
+type NamedTable struct {
+	ID int64 `meddler:"id,pk"`
+}
+
+func (*NamedTable) TableName() string {
+	return "custom_table"
+}
+
+func TestNameMapper(t *testing.T) {
+	d := *PostgreSQL
+	d.NameMapper = SnakeCase
+	names, err := d.Columns(&MappedPerson{}, true)
+	if err != nil {
+		t.Errorf("Error getting Columns: %v", err)
+	}
+	expected := []string{"id", "first_name", "home_email", "other"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected columns %v, found %v", expected, names)
+	}
+
+	// each mapper gets its own cached metadata
+	d.NameMapper = CamelCase
+	names, err = d.Columns(&MappedPerson{}, true)
+	if err != nil {
+		t.Errorf("Error getting Columns: %v", err)
+	}
+	expected = []string{"id", "firstName", "homeEmail", "other"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected columns %v, found %v", expected, names)
+	}
+	names, err = PostgreSQL.Columns(&MappedPerson{}, true)
+	if err != nil {
+		t.Errorf("Error getting Columns: %v", err)
+	}
+	expected = []string{"id", "FirstName", "HomeEmail", "other"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected columns %v, found %v", expected, names)
+	}
+}
+
+func prefixMapper(prefix string) func(string) string {
+	return func(name string) string {
+		return prefix + SnakeCase(name)
+	}
+}
+
+func TestNameMapperClosures(t *testing.T) {
+	// closures from the same function literal must not share cached metadata
+	a, b := *PostgreSQL, *PostgreSQL
+	a.NameMapper = prefixMapper("a_")
+	b.NameMapper = prefixMapper("b_")
+	for _, test := range []struct {
+		d        *Database
+		expected []string
+	}{
+		{&a, []string{"id", "a_first_name", "a_home_email", "other"}},
+		{&b, []string{"id", "b_first_name", "b_home_email", "other"}},
+	} {
+		names, err := test.d.Columns(&MappedPerson{}, true)
+		if err != nil {
+			t.Errorf("Error getting Columns: %v", err)
+		}
+		if !reflect.DeepEqual(names, test.expected) {
+			t.Errorf("Expected columns %v, found %v", test.expected, names)
+		}
+	}
+}
+
+func TestNameMapperCache(t *testing.T) {
+	// a custom mapper is only called until the struct has been cached, and
+	// may use meddler itself
+	calls := 0
+	d := *PostgreSQL
+	d.NameMapper = func(name string) string {
+		calls++
+		if _, err := PostgreSQL.Columns(&Label{}, true); err != nil {
+			t.Errorf("Error getting Columns in the mapper: %v", err)
+		}
+		return SnakeCase(name)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := d.Columns(&MappedPerson{}, true); err != nil {
+			t.Errorf("Error getting Columns: %v", err)
+		}
+	}
+	if calls != 4 {
+		t.Errorf("Expected the mapper to be called once for each of 4 fields, found %d calls", calls)
+	}
+
+	// Database values with the same NameMapperKey share the cache
+	a, b := *PostgreSQL, *PostgreSQL
+	a.NameMapper, a.NameMapperKey = SnakeCase, "test-snake"
+	b.NameMapper, b.NameMapperKey = func(name string) string {
+		t.Errorf("Expected the metadata of the first Database to be used for %s", name)
+		return SnakeCase(name)
+	}, "test-snake"
+	for _, d := range []*Database{&a, &b} {
+		names, err := d.Columns(&MappedPerson{}, true)
+		if err != nil {
+			t.Errorf("Error getting Columns: %v", err)
+		}
+		if expected := []string{"id", "first_name", "home_email", "other"}; !reflect.DeepEqual(names, expected) {
+			t.Errorf("Expected columns %v, found %v", expected, names)
+		}
+	}
+}
+
+func TestTableName(t *testing.T) {
+	d := *PostgreSQL
+	d.NameMapper = SnakeCase
+	tests := []struct {
+		src      interface{}
+		expected string
+	}{
+		{&MappedPerson{}, "mapped_person"},
+		{MappedPerson{}, "mapped_person"},
+		{[]*MappedPerson{}, "mapped_person"},
+		{&NamedTable{}, "custom_table"},
+		{[]NamedTable{}, "custom_table"},
+	}
+	for _, test := range tests {
+		name, err := d.TableName(test.src)
+		if err != nil {
+			t.Errorf("TableName(%T) error: %v", test.src, err)
+		} else if name != test.expected {
+			t.Errorf("TableName(%T): want %q, got %q", test.src, test.expected, name)
+		}
+	}
+
+	if name, err := PostgreSQL.TableName(&MappedPerson{}); err != nil || name != "MappedPerson" {
+		t.Errorf("TableName without NameMapper: want MappedPerson, got %q (%v)", name, err)
+	}
+	if _, err := d.TableName(new(int)); err == nil {
+		t.Errorf("TableName(*int): want error, got none")
+	}
+}
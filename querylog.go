@@ -0,0 +1,61 @@
+package meddler
+
+import (
+	"context"
+	"time"
+)
+
+// QueryLogger is implemented by types that want to know about every
+// statement a Database runs, e.g. to write it to a log or to record it as
+// a tracing span. Set it as the QueryLogger of the Database.
+//
+// LogQuery is called once each statement has run, from the goroutine that
+// ran it, with the context of the calling function (e.g. LoadContext). It
+// must not keep the event after it returns.
+type QueryLogger interface {
+	LogQuery(ctx context.Context, event *QueryEvent)
+}
+
+// QueryLoggerFunc adapts an ordinary function to the QueryLogger interface.
+type QueryLoggerFunc func(ctx context.Context, event *QueryEvent)
+
+// LogQuery calls f(ctx, event).
+func (f QueryLoggerFunc) LogQuery(ctx context.Context, event *QueryEvent) {
+	f(ctx, event)
+}
+
+// QueryEvent describes a statement run by a Database.
+type QueryEvent struct {
+	Query    string        // the SQL of the statement
+	Args     []interface{} // its arguments, after the RedactArgs function of the Database
+	Start    time.Time     // when the statement was started
+	Duration time.Duration // how long it took, including preparing it if needed
+
+	// RowsAffected is the number of rows changed by a statement that does
+	// not return rows, or -1 for queries and when the driver cannot tell.
+	RowsAffected int64
+
+	// Err is the error from running the statement, if any. For an insert
+	// that reads back its generated key, e.g. with RETURNING or OUTPUT
+	// INSERTED, it is the error from reading the key, which includes
+	// sql.ErrNoRows if the statement returned no row.
+	Err error
+}
+
+// logQuery passes a statement that has finished to the QueryLogger.
+func (d *Database) logQuery(ctx context.Context, query string, args []interface{}, start time.Time, rowsAffected int64, err error) {
+	if d.QueryLogger == nil {
+		return
+	}
+	if d.RedactArgs != nil {
+		args = d.RedactArgs(query, append([]interface{}(nil), args...))
+	}
+	d.QueryLogger.LogQuery(ctx, &QueryEvent{
+		Query:        query,
+		Args:         args,
+		Start:        start,
+		Duration:     time.Since(start),
+		RowsAffected: rowsAffected,
+		Err:          err,
+	})
+}
@@ -0,0 +1,77 @@
+package meddler
+
+import (
+	"context"
+	"testing"
+)
+
+func TestQueryLogger(t *testing.T) {
+	once.Do(setup)
+	insertAliceBob(t)
+
+	type key struct{}
+	var events []QueryEvent
+	var contexts []context.Context
+	d := *SQLite
+	d.QueryLogger = QueryLoggerFunc(func(ctx context.Context, event *QueryEvent) {
+		events = append(events, *event)
+		contexts = append(contexts, ctx)
+	})
+	ctx := context.WithValue(context.Background(), key{}, true)
+
+	elt := new(Person)
+	if err := d.LoadContext(ctx, db, "person", elt, 1); err != nil {
+		t.Errorf("Load error on Alice: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("Expected one event for Load, found %d", len(events))
+	}
+	if events[0].Err != nil || events[0].RowsAffected != -1 || len(events[0].Args) != 1 || events[0].Args[0] != int64(1) {
+		t.Errorf("Unexpected event for Load: %+v", events[0])
+	}
+	if events[0].Start.IsZero() || events[0].Duration < 0 {
+		t.Errorf("Expected Load to be timed, found %v for %v", events[0].Start, events[0].Duration)
+	}
+	if contexts[0].Value(key{}) == nil {
+		t.Errorf("QueryLogger did not receive the caller context")
+	}
+
+	// arguments are redacted without changing the ones used by the query
+	d.RedactArgs = func(query string, args []interface{}) []interface{} {
+		for i := range args {
+			if args[i] == "alice@example.com" {
+				args[i] = "***"
+			}
+		}
+		return args
+	}
+	elt.Email = "alice@example.com"
+	if err := d.UpdateColumns(db, "person", elt, "Email"); err != nil {
+		t.Errorf("UpdateColumns error: %v", err)
+	}
+	if len(events) != 2 || events[1].RowsAffected != 1 || events[1].Args[0] != "***" {
+		t.Errorf("Expected a redacted event for UpdateColumns, found %+v", events[1:])
+	}
+	reloaded := new(Person)
+	if err := Load(db, "person", reloaded, 1); err != nil || reloaded.Email != "alice@example.com" {
+		t.Errorf("Expected the email to be saved as given, found %q (%v)", reloaded.Email, err)
+	}
+
+	var names []string
+	if err := d.QueryAll(db, &names, "select nonesuch from person"); err == nil {
+		t.Errorf("QueryAll with a bad column: want error, got none")
+	}
+	if len(events) != 3 || events[2].Err == nil || events[2].Query != "select nonesuch from person" {
+		t.Errorf("Expected an event with the error, found %+v", events[2:])
+	}
+
+	// an insert with RETURNING only fails when its row is scanned
+	d.UseReturningToGetID = true
+	if err := d.Insert(db, "nonesuch", &Person{Name: "Carol"}); err == nil {
+		t.Errorf("Insert into a missing table: want error, got none")
+	}
+	if len(events) != 4 || events[3].Err == nil {
+		t.Errorf("Expected an event with the error for Insert, found %+v", events[3:])
+	}
+	db.Exec("delete from person")
+}
@@ -1,25 +1,41 @@
 package meddler
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
 	"fmt"
 	"log"
 	"reflect"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
-// the name of our struct tag
+// the name of our struct tag, unless Database.TagName says otherwise
 const tagName = "meddler"
 
 // Database contains database-specific options.
-// MySQL, PostgreSQL, and SQLite are provided for convenience.
+// MySQL, PostgreSQL, SQLite, SQLServer, and Oracle are provided for convenience.
 // Setting Default to any of these lets you use the package-level convenience functions.
 type Database struct {
-	Quote               string // the quote character for table and column names
-	Placeholder         string // the placeholder style to use in generated queries
-	UseReturningToGetID bool   // use PostgreSQL-style RETURNING "ID" instead of calling sql.Result.LastInsertID
+	Quote               string     // the quote character for table and column names
+	QuoteEnd            string     // the closing quote character, if different from Quote
+	Placeholder         string     // the placeholder style to use in generated queries
+	UseReturningToGetID bool       // use PostgreSQL-style RETURNING "ID" instead of calling sql.Result.LastInsertID
+	IDStrategy          IDStrategy // how to retrieve generated primary keys, if not with LastInsertId or UseReturningToGetID
+	MaxPlaceholders     int        // the most placeholders allowed in a single query, or 0 for no limit
+	MaxInsertRows       int        // the most rows in a single multi-row INSERT, or 0 for no limit
+
+	// UpsertSyntax selects the clause used by Upsert to turn an INSERT
+	// into an update when a row with the same key already exists.
+	UpsertSyntax UpsertSyntax
+
+	// LimitSyntax selects the clause used by Select to limit the number
+	// of rows returned.
+	LimitSyntax LimitSyntax
 
 	// StmtCacheFunc is a function that takes a DB interface and a query string
 	// and returns a prepared statement or an error. If the returned statement
@@ -30,65 +46,357 @@ type Database struct {
 	//
 	// The default nil value means that no prepared statement is used.
 	StmtCacheFunc func(DB, string) (*sql.Stmt, error)
+
+	// TagName is the struct tag read for column names and options, e.g.
+	// "db" for structs annotated for sqlx. The default "" means "meddler".
+	TagName string
+
+	// JSONTagFallback makes fields without a TagName tag take their column
+	// name from their json tag, if they have one. Only the name is used, as
+	// the json options are not meddlers.
+	JSONTagFallback bool
+
+	// NameMapper maps the names of fields that have no column name in their
+	// tag to column names, e.g. SnakeCase. It also maps struct type names
+	// to table names in TableName. The default nil value uses field names
+	// as they are. The column names are cached with the rest of the struct
+	// metadata, so the mapper is not called again for the same struct.
+	NameMapper func(string) string
+
+	// NameMapperKey names the mapping done by NameMapper, for caching. The
+	// default "" means that the metadata found with a NameMapper other
+	// than SnakeCase, LowerCase, and CamelCase is cached for this Database
+	// only. Database values that share a key must map names the same way.
+	NameMapperKey string
+
+	// Now returns the time used for the columns tagged created, updated,
+	// and softdelete.
+	// The default nil value uses time.Now.
+	Now func() time.Time
+
+	// Registry holds meddlers for this Database only, which take precedence
+	// over the ones registered globally with Register. The default nil value
+	// means that only the global meddlers are used.
+	Registry *Registry
+
+	// StmtCacheContextFunc is the context-aware counterpart of StmtCacheFunc.
+	// It receives the context of the calling function (e.g. LoadContext) and
+	// must return a statement valid for the provided DBContext. If it is set,
+	// StmtCacheFunc is not used.
+	StmtCacheContextFunc func(context.Context, DBContext, string) (*sql.Stmt, error)
+
+	// StmtCache, if set, supplies the prepared statements for queries, and
+	// takes precedence over StmtCacheContextFunc and StmtCacheFunc. Unlike
+	// those, the cache knows when a statement has been started, so it never
+	// closes one that a query is about to run.
+	StmtCache *StmtCache
+
+	// QueryLogger, if set, is told about every statement run by this
+	// Database, e.g. to log it or to record it for tracing.
+	QueryLogger QueryLogger
+
+	// RedactArgs, if set, filters the arguments of each statement before
+	// they are passed to QueryLogger, e.g. to hide passwords. It receives
+	// a copy of the arguments, which it may change in place.
+	RedactArgs func(query string, args []interface{}) []interface{}
+
+	// Unmatched selects what happens when a column has no struct field,
+	// and, in strict mode, when a struct field has no column in a query
+	// result. The default follows the package-level Debug flag.
+	Unmatched UnmatchedMode
+
+	// Logger receives the messages about unmatched columns. The default
+	// nil value uses the standard logger of the log package.
+	Logger Logger
+}
+
+// UnmatchedMode selects how a Database reports columns and struct fields
+// that do not match up.
+type UnmatchedMode int
+
+const (
+	// UnmatchedDefault logs columns that have no struct field if Debug is
+	// true, and ignores them otherwise.
+	UnmatchedDefault UnmatchedMode = iota
+
+	// UnmatchedSilent ignores columns that have no struct field.
+	UnmatchedSilent
+
+	// UnmatchedLog logs columns that have no struct field.
+	UnmatchedLog
+
+	// UnmatchedStrict returns an error wrapping ErrUnmatchedColumn for a
+	// column that has no struct field, and one wrapping ErrMissingColumn
+	// when a query result has no column for a struct field. This catches a
+	// schema that has drifted from the structs, e.g. in tests.
+	UnmatchedStrict
+)
+
+// Logger is the interface used to log messages, matching *log.Logger.
+type Logger interface {
+	Printf(format string, v ...interface{})
 }
 
+// UpsertSyntax is the form of the clause used by Upsert.
+type UpsertSyntax int
+
+const (
+	// NoUpsert means the database has no supported upsert clause.
+	NoUpsert UpsertSyntax = iota
+
+	// OnConflict is the PostgreSQL and SQLite form:
+	//   ON CONFLICT ("id") DO UPDATE SET "name"=EXCLUDED."name"
+	OnConflict
+
+	// OnDuplicateKey is the MySQL form:
+	//   ON DUPLICATE KEY UPDATE `name`=VALUES(`name`)
+	OnDuplicateKey
+)
+
+// LimitSyntax is the form of the clause used by Select for Limit and
+// Offset.
+type LimitSyntax int
+
+const (
+	// LimitOffset is the MySQL, PostgreSQL, and SQLite form:
+	//	LIMIT 10 OFFSET 20
+	LimitOffset LimitSyntax = iota
+
+	// OffsetFetch is the Oracle form:
+	//	OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY
+	OffsetFetch
+
+	// OrderedOffsetFetch is the SQL Server form, which is like OffsetFetch
+	// but only allowed after ORDER BY. Without OrderBy, Select orders the
+	// rows by (SELECT NULL).
+	OrderedOffsetFetch
+)
+
+// IDStrategy is the way Insert retrieves a primary key generated by the
+// database.
+type IDStrategy int
+
+const (
+	// IDLastInsertID calls sql.Result.LastInsertId, or uses RETURNING if
+	// UseReturningToGetID is set.
+	IDLastInsertID IDStrategy = iota
+
+	// IDReturning is the PostgreSQL form, equivalent to setting
+	// UseReturningToGetID:
+	//	INSERT INTO "t" ("name") VALUES ($1) RETURNING "id"
+	IDReturning
+
+	// IDOutputInserted is the SQL Server form:
+	//	INSERT INTO [t] ([name]) OUTPUT INSERTED.[id] VALUES (@p1)
+	IDOutputInserted
+
+	// IDReturningInto is the Oracle form, which passes an sql.Out
+	// argument to receive the key:
+	//	INSERT INTO "t" ("name") VALUES (:1) RETURNING "id" INTO :2
+	IDReturningInto
+)
+
 var MySQL = &Database{
 	Quote:               "`",
 	Placeholder:         "?",
 	UseReturningToGetID: false,
+	MaxPlaceholders:     65535,
+	UpsertSyntax:        OnDuplicateKey,
 }
 
 var PostgreSQL = &Database{
 	Quote:               `"`,
 	Placeholder:         "$1",
 	UseReturningToGetID: true,
+	MaxPlaceholders:     65535,
+	UpsertSyntax:        OnConflict,
 }
 
 var SQLite = &Database{
 	Quote:               `"`,
 	Placeholder:         "?",
 	UseReturningToGetID: false,
+	MaxPlaceholders:     999,
+	UpsertSyntax:        OnConflict,
+}
+
+var SQLServer = &Database{
+	Quote:           "[",
+	QuoteEnd:        "]",
+	Placeholder:     "@p1",
+	IDStrategy:      IDOutputInserted,
+	MaxPlaceholders: 2100,
+	MaxInsertRows:   1000,
+	UpsertSyntax:    NoUpsert,
+	LimitSyntax:     OrderedOffsetFetch,
+}
+
+var Oracle = &Database{
+	Quote:           `"`,
+	Placeholder:     ":1",
+	IDStrategy:      IDReturningInto,
+	MaxPlaceholders: 65535,
+	MaxInsertRows:   1,
+	UpsertSyntax:    NoUpsert,
+	LimitSyntax:     OffsetFetch,
 }
 
 var Default = MySQL
 
 func (d *Database) quoted(s string) string {
+	if d.QuoteEnd != "" {
+		return d.Quote + s + d.QuoteEnd
+	}
 	return d.Quote + s + d.Quote
 }
 
+// idStrategy returns the IDStrategy to use, taking UseReturningToGetID
+// into account.
+func (d *Database) idStrategy() IDStrategy {
+	if d.IDStrategy == IDLastInsertID && d.UseReturningToGetID {
+		return IDReturning
+	}
+	return d.IDStrategy
+}
+
+// unmatched reports a column that has no struct field according to the
+// Unmatched mode, returning an error in strict mode.
+func (d *Database) unmatched(op, column string) error {
+	switch d.Unmatched {
+	case UnmatchedStrict:
+		return &ColumnError{Column: column, Op: op, Err: ErrUnmatchedColumn}
+	case UnmatchedDefault:
+		if !Debug {
+			return nil
+		}
+	case UnmatchedSilent:
+		return nil
+	}
+	if d.Logger != nil {
+		d.Logger.Printf("%s: column [%s] not found in struct", op, column)
+	} else {
+		log.Printf("%s: column [%s] not found in struct", op, column)
+	}
+	return nil
+}
+
 func (d *Database) placeholder(n int) string {
 	return strings.Replace(d.Placeholder, "1", strconv.FormatInt(int64(n), 10), 1)
 }
 
-// Debug enables debug mode, where unused columns and struct fields will be logged
+// Debug enables debug mode, where unused columns will be logged by the
+// Databases whose Unmatched mode is UnmatchedDefault
 var Debug = true
 
 type structField struct {
 	column     string
-	index      int
+	index      []int
 	primaryKey bool
+	version    bool
+	created    bool
+	updated    bool
+	softDelete bool
 	meddler    Meddler
 }
 
 type structData struct {
-	columns []string
-	fields  map[string]*structField
-	pk      string
+	columns    []string
+	fields     map[string]*structField
+	pk         []string
+	version    string
+	created    []string
+	updated    []string
+	softDelete string
+}
+
+// cache reflection data, which depends on the settings of the Database
+// that read it
+type fieldsSettings struct {
+	registry        *Registry
+	tagName         string
+	jsonTagFallback bool
+	nameMapper      interface{}
+}
+
+type fieldsKey struct {
+	settings fieldsSettings
+	dstType  reflect.Type
+}
+
+// fieldsSettings returns the settings of the Database that affect its
+// reflection data.
+func (d *Database) fieldsSettings() fieldsSettings {
+	return fieldsSettings{
+		registry:        d.Registry,
+		tagName:         d.tagName(),
+		jsonTagFallback: d.JSONTagFallback,
+		nameMapper:      d.nameMapperID(),
+	}
+}
+
+func (d *Database) tagName() string {
+	if d.TagName == "" {
+		return tagName
+	}
+	return d.TagName
+}
+
+// fieldTag returns the parts of the struct tag of f: the column name
+// followed by its options.
+func (d *Database) fieldTag(f reflect.StructField) []string {
+	if value, present := f.Tag.Lookup(d.tagName()); present {
+		return strings.Split(value, ",")
+	}
+	if d.JSONTagFallback {
+		if value, present := f.Tag.Lookup("json"); present {
+			return strings.Split(value, ",")[:1]
+		}
+	}
+	return []string{""}
 }
 
-// cache reflection data
-var fieldsCache = make(map[reflect.Type]*structData)
+var fieldsCache = make(map[fieldsKey]*structData)
 var fieldsCacheMutex sync.Mutex
 
-// getFields gathers the list of columns from a struct using reflection.
-func getFields(dstType reflect.Type) (*structData, error) {
+func resetFieldsCache() {
 	fieldsCacheMutex.Lock()
 	defer fieldsCacheMutex.Unlock()
 
-	if result, present := fieldsCache[dstType]; present {
+	fieldsCache = make(map[fieldsKey]*structData)
+}
+
+// getFields gathers the list of columns from a struct using reflection.
+// Fields of embedded structs are included as if they were fields of the
+// outer struct.
+func (d *Database) getFields(dstType reflect.Type) (*structData, error) {
+	key := fieldsKey{settings: d.fieldsSettings(), dstType: dstType}
+	fieldsCacheMutex.Lock()
+	result, present := fieldsCache[key]
+	fieldsCacheMutex.Unlock()
+	if present {
 		return result, nil
 	}
 
+	// the lock is not held while reading the struct, as that calls the
+	// NameMapper and meddlers, which may use meddler themselves
+	data, err := d.readFields(dstType)
+	if err != nil {
+		return nil, err
+	}
+
+	fieldsCacheMutex.Lock()
+	defer fieldsCacheMutex.Unlock()
+	if result, present := fieldsCache[key]; present {
+		// another goroutine read it first
+		return result, nil
+	}
+	fieldsCache[key] = data
+	return data, nil
+}
+
+// readFields gathers the reflection data of a struct for getFields.
+func (d *Database) readFields(dstType reflect.Type) (*structData, error) {
+
 	// make sure dst is a non-nil pointer to a struct
 	if dstType.Kind() != reflect.Ptr {
 		return nil, fmt.Errorf("meddler called with non-pointer destination %v", dstType)
@@ -99,86 +407,255 @@ func getFields(dstType reflect.Type) (*structData, error) {
 	}
 
 	// gather the list of fields in the struct
+	var candidates []*structField
+	visited := map[reflect.Type]bool{structType: true}
+	if err := d.collectFields(structType, nil, "", visited, &candidates); err != nil {
+		return nil, err
+	}
+
+	// resolve columns found more than once the way Go resolves field
+	// names: the shallowest field wins, and a tie is an error
+	depth := make(map[string]int)
+	for _, field := range candidates {
+		if min, present := depth[field.column]; !present || len(field.index) < min {
+			depth[field.column] = len(field.index)
+		}
+	}
+
 	data := new(structData)
 	data.fields = make(map[string]*structField)
+	for _, field := range candidates {
+		if len(field.index) > depth[field.column] {
+			continue
+		}
+		if _, present := data.fields[field.column]; present {
+			return nil, fmt.Errorf("meddler found multiple fields for column %s", field.column)
+		}
+
+		data.fields[field.column] = field
+		data.columns = append(data.columns, field.column)
+		if field.primaryKey {
+			data.pk = append(data.pk, field.column)
+		}
+		if field.version {
+			if data.version != "" {
+				return nil, fmt.Errorf("meddler found multiple version columns: %s and %s", data.version, field.column)
+			}
+			data.version = field.column
+		}
+		if field.created {
+			data.created = append(data.created, field.column)
+		}
+		if field.updated {
+			data.updated = append(data.updated, field.column)
+		}
+		if field.softDelete {
+			if data.softDelete != "" {
+				return nil, fmt.Errorf("meddler found multiple softdelete columns: %s and %s", data.softDelete, field.column)
+			}
+			data.softDelete = field.column
+		}
+	}
 
+	return data, nil
+}
+
+// collectFields gathers the fields of structType, recursing into embedded
+// structs. index is the index path of structType within the outer struct,
+// and prefix is prepended to every column name found.
+func (d *Database) collectFields(structType reflect.Type, index []int, prefix string, visited map[reflect.Type]bool, out *[]*structField) error {
 	for i := 0; i < structType.NumField(); i++ {
 		f := structType.Field(i)
+		fieldIndex := append(append([]int(nil), index...), i)
 
-		// skip non-exported fields
-		if f.PkgPath != "" {
+		// the change tracking snapshot is not a column
+		if f.Anonymous && (f.Type == snapshotType || f.Type == reflect.PtrTo(snapshotType)) {
 			continue
 		}
 
 		// examine the tag for metadata
-		tag := strings.Split(f.Tag.Get(tagName), ",")
+		tag := d.fieldTag(f)
 
 		// was this field marked for skipping?
 		if len(tag) > 0 && tag[0] == "-" {
 			continue
 		}
 
+		// flatten embedded structs into the outer struct
+		if embedded, embedPrefix, ok := embeddedStruct(f, tag); ok {
+			// nil pointers to non-exported types cannot be allocated
+			if f.PkgPath != "" && f.Type.Kind() == reflect.Ptr {
+				continue
+			}
+			if visited[embedded] {
+				continue
+			}
+			visited[embedded] = true
+			err := d.collectFields(embedded, fieldIndex, prefix+embedPrefix, visited, out)
+			delete(visited, embedded)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		// skip non-exported fields
+		if f.PkgPath != "" {
+			continue
+		}
+
 		// default to the field name
-		name := f.Name
+		name := d.mapName(f.Name)
 
 		// the tag can override the field name
 		if len(tag) > 0 && tag[0] != "" {
 			name = tag[0]
 		}
+		name = prefix + name
 
 		// check for a meddler
-		var meddler Meddler = registry["identity"]
+		primaryKey, version, created, updated, softDelete := false, false, false, false, false
+		meddler, _ := d.meddler("identity")
+		if f.Type.Kind() == reflect.Array && f.Type.Elem().Kind() == reflect.Uint8 && !reflect.PtrTo(f.Type).Implements(scannerType) {
+			// drivers only deal in byte slices
+			meddler = byteArrayMeddler{}
+		}
 		for j := 1; j < len(tag); j++ {
 			if tag[j] == "pk" {
 				if f.Type.Kind() == reflect.Ptr {
-					return nil, fmt.Errorf("meddler found field %s which is marked as the primary key but is a pointer", f.Name)
+					return fmt.Errorf("meddler found field %s which is marked as the primary key but is a pointer", f.Name)
 				}
 
-				// make sure it is an int of some kind
-				switch f.Type.Kind() {
-				case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-				case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-				default:
-					return nil, fmt.Errorf("meddler found field %s which is marked as the primary key, but is not an integer type", f.Name)
+				// make sure it is a supported key type
+				if !validPrimaryKeyType(f.Type) {
+					return fmt.Errorf("meddler found field %s which is marked as the primary key, but is not an integer, string, [16]byte, or driver.Valuer type", f.Name)
 				}
 
-				if data.pk != "" {
-					return nil, fmt.Errorf("meddler found field %s which is marked as the primary key, but a primary key field was already found", f.Name)
+				primaryKey = true
+			} else if tag[j] == "version" {
+				if !isIntegerKind(f.Type) {
+					return fmt.Errorf("meddler found field %s which is marked as the version, but is not an integer", f.Name)
+				}
+				version = true
+			} else if tag[j] == "created" || tag[j] == "updated" || tag[j] == "softdelete" {
+				if f.Type != timeType && f.Type != reflect.PtrTo(timeType) {
+					return fmt.Errorf("meddler found field %s which is marked as %s, but is not a time.Time or *time.Time", f.Name, tag[j])
 				}
-				data.pk = name
-			} else if m, present := registry[tag[j]]; present {
+				created = created || tag[j] == "created"
+				updated = updated || tag[j] == "updated"
+				softDelete = softDelete || tag[j] == "softdelete"
+			} else if strings.HasPrefix(tag[j], "prefix=") {
+				return fmt.Errorf("meddler found field %s with a column prefix, but it is not an embedded struct", f.Name)
+			} else if m, present := d.meddler(tag[j]); present {
 				meddler = m
 			} else {
-				return nil, fmt.Errorf("meddler found field %s with meddler %s, but that meddler is not registered", f.Name, tag[j])
+				return fmt.Errorf("meddler found field %s with meddler %s, but that meddler is not registered", f.Name, tag[j])
 			}
 		}
 
-		if _, present := data.fields[name]; present {
-			return nil, fmt.Errorf("meddler found multiple fields for column %s", name)
+		// rows that are not deleted must have NULL in the softdelete column
+		if softDelete && f.Type == timeType {
+			if value, err := meddler.PreWrite(time.Time{}); err != nil || value != nil {
+				return fmt.Errorf("meddler found field %s which is marked as softdelete, but its meddler does not write the zero time as NULL; use *time.Time or a meddler such as utctimez", f.Name)
+			}
 		}
-		data.fields[name] = &structField{
+
+		*out = append(*out, &structField{
 			column:     name,
-			primaryKey: name == data.pk,
-			index:      i,
+			primaryKey: primaryKey,
+			version:    version,
+			created:    created,
+			updated:    updated,
+			softDelete: softDelete,
+			index:      fieldIndex,
 			meddler:    meddler,
+		})
+	}
+
+	return nil
+}
+
+// embeddedStruct reports whether f is an embedded struct (or pointer to
+// struct) whose fields should be flattened into the outer struct, and if
+// so returns the struct type and the column prefix from its tag, e.g.
+//
+//	AuditInfo `meddler:",prefix=audit_"`
+//
+// Embedded structs with a column name or a meddler in their tag, and types
+// that handle their own database conversion such as time.Time, are
+// treated as a single column.
+func embeddedStruct(f reflect.StructField, tag []string) (reflect.Type, string, bool) {
+	if !f.Anonymous {
+		return nil, "", false
+	}
+	t := f.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == timeType {
+		return nil, "", false
+	}
+	if reflect.PtrTo(t).Implements(scannerType) || t.Implements(valuerType) {
+		return nil, "", false
+	}
+	if len(tag) > 0 && tag[0] != "" {
+		return nil, "", false
+	}
+
+	prefix := ""
+	for _, opt := range tag[1:] {
+		if !strings.HasPrefix(opt, "prefix=") {
+			return nil, "", false
 		}
-		data.columns = append(data.columns, name)
+		prefix = strings.TrimPrefix(opt, "prefix=")
 	}
+	return t, prefix, true
+}
 
-	fieldsCache[dstType] = data
-	return data, nil
+var timeType = reflect.TypeOf(time.Time{})
+
+// fieldByIndex returns the struct field with the given index path,
+// allocating any nil embedded struct pointers along the way.
+func fieldByIndex(structVal reflect.Value, index []int) reflect.Value {
+	v := structVal
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// fieldByIndexNoAlloc is like fieldByIndex, but reports false instead of
+// allocating if a nil embedded struct pointer is in the way.
+func fieldByIndexNoAlloc(structVal reflect.Value, index []int) (reflect.Value, bool) {
+	v := structVal
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
 }
 
 // Columns returns a list of column names for its input struct.
 func (d *Database) Columns(src interface{}, includePk bool) ([]string, error) {
-	data, err := getFields(reflect.TypeOf(src))
+	data, err := d.getFields(reflect.TypeOf(src))
 	if err != nil {
 		return nil, err
 	}
 
 	var names []string
 	for _, elt := range data.columns {
-		if !includePk && elt == data.pk {
+		if !includePk && data.fields[elt].primaryKey {
 			continue
 		}
 		names = append(names, elt)
@@ -196,7 +673,7 @@ func Columns(src interface{}, includePk bool) ([]string, error) {
 //   `column1`,`column2`,...
 // using Quote as the quote character.
 func (d *Database) ColumnsQuoted(src interface{}, includePk bool) (string, error) {
-	unquoted, err := Columns(src, includePk)
+	unquoted, err := d.Columns(src, includePk)
 	if err != nil {
 		return "", err
 	}
@@ -214,66 +691,380 @@ func ColumnsQuoted(src interface{}, includePk bool) (string, error) {
 	return Default.ColumnsQuoted(src, includePk)
 }
 
-// PrimaryKey returns the name and value of the primary key field. The name
-// is the empty string if there is not primary key field marked.
-func (d *Database) PrimaryKey(src interface{}) (name string, pk int64, err error) {
-	data, err := getFields(reflect.TypeOf(src))
+// validPrimaryKeyType reports whether a field of type t can be used as the
+// primary key.
+func validPrimaryKeyType(t reflect.Type) bool {
+	if t.Implements(valuerType) || reflect.PtrTo(t).Implements(valuerType) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	case reflect.String:
+		return true
+	case reflect.Array:
+		return t.Len() == 16 && t.Elem().Kind() == reflect.Uint8
+	}
+	return false
+}
+
+var valuerType = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+var scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+
+// primaryKeyFields returns the names of the primary key columns and the
+// addressable struct fields holding them, allocating nil embedded structs
+// on the way. The names are empty if there is no primary key field marked.
+func (d *Database) primaryKeyFields(src interface{}) ([]string, []reflect.Value, error) {
+	data, err := d.getFields(reflect.TypeOf(src))
 	if err != nil {
-		return "", 0, err
+		return nil, nil, err
 	}
 
-	if data.pk == "" {
-		return "", 0, nil
+	structVal := reflect.ValueOf(src).Elem()
+	var fields []reflect.Value
+	for _, name := range data.pk {
+		fields = append(fields, fieldByIndex(structVal, data.fields[name].index))
 	}
 
-	name = data.pk
-	field := reflect.ValueOf(src).Elem().Field(data.fields[name].index)
-	switch field.Type().Kind() {
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		pk = field.Int()
+	return data.pk, fields, nil
+}
+
+// versionField returns the name and field of the version column of src,
+// or an empty name if it has none.
+func (d *Database) versionField(src interface{}) (string, reflect.Value, error) {
+	data, err := d.getFields(reflect.TypeOf(src))
+	if err != nil {
+		return "", reflect.Value{}, err
+	}
+	if data.version == "" {
+		return "", reflect.Value{}, nil
+	}
+
+	structVal := reflect.ValueOf(src).Elem()
+	return data.version, fieldByIndex(structVal, data.fields[data.version].index), nil
+}
+
+// initVersion sets the version of src to 1 if it has a version column that
+// is zero, as for a record about to be inserted.
+func (d *Database) initVersion(src interface{}) error {
+	name, field, err := d.versionField(src)
+	if err != nil || name == "" || !field.IsZero() {
+		return err
+	}
+	setInteger(field, 1)
+	return nil
+}
+
+func (d *Database) now() time.Time {
+	if d.Now != nil {
+		return d.Now()
+	}
+	return time.Now()
+}
+
+// setTimestamps sets the columns of src tagged updated to the current
+// time, as for a record about to be written, and returns their names. If
+// insert is set, the columns tagged created are set too, unless they
+// already have a value.
+func (d *Database) setTimestamps(src interface{}, insert bool) ([]string, error) {
+	data, err := d.getFields(reflect.TypeOf(src))
+	if err != nil {
+		return nil, err
+	}
+	if len(data.updated) == 0 && (!insert || len(data.created) == 0) {
+		return nil, nil
+	}
+
+	now := d.now()
+	structVal := reflect.ValueOf(src).Elem()
+	set := func(name string, onlyIfZero bool) {
+		field := fieldByIndex(structVal, data.fields[name].index)
+		if onlyIfZero && !field.IsZero() && !(field.Kind() == reflect.Ptr && field.Elem().IsZero()) {
+			return
+		}
+		if field.Kind() == reflect.Ptr {
+			t := now
+			field.Set(reflect.ValueOf(&t))
+		} else {
+			field.Set(reflect.ValueOf(now))
+		}
+	}
+	if insert {
+		for _, name := range data.created {
+			set(name, true)
+		}
+	}
+	for _, name := range data.updated {
+		set(name, false)
+	}
+
+	return data.updated, nil
+}
+
+// softDeleteColumn returns the name of the column of src tagged
+// softdelete, or an empty name if it has none.
+func (d *Database) softDeleteColumn(src interface{}) (string, error) {
+	data, err := d.getFields(reflect.TypeOf(src))
+	if err != nil {
+		return "", err
+	}
+	return data.softDelete, nil
+}
+
+// createdColumns returns the names of the columns of src tagged created.
+func (d *Database) createdColumns(src interface{}) ([]string, error) {
+	data, err := d.getFields(reflect.TypeOf(src))
+	if err != nil {
+		return nil, err
+	}
+	return data.created, nil
+}
+
+// saveWritten copies the fields of src that the functions writing it set
+// before running their query: the version and the created and updated
+// times. It returns a function that puts the copies back, for when the
+// write fails.
+func (d *Database) saveWritten(src interface{}) (func(), error) {
+	data, err := d.getFields(reflect.TypeOf(src))
+	if err != nil {
+		return nil, err
+	}
+	names := append(append([]string(nil), data.created...), data.updated...)
+	if data.version != "" {
+		names = append(names, data.version)
+	}
+	return saveFields(reflect.ValueOf(src).Elem(), data, names), nil
+}
+
+// saveFields copies the named fields of structVal, and returns a function
+// that puts the copies back.
+func saveFields(structVal reflect.Value, data *structData, names []string) func() {
+	fields := make([]reflect.Value, len(names))
+	saved := make([]reflect.Value, len(names))
+	for i, name := range names {
+		fields[i] = fieldByIndex(structVal, data.fields[name].index)
+		saved[i] = reflect.New(fields[i].Type()).Elem()
+		saved[i].Set(fields[i])
+	}
+	return func() {
+		for i, field := range fields {
+			field.Set(saved[i])
+		}
+	}
+}
+
+// integerValue returns the value of an integer field of any kind.
+func integerValue(field reflect.Value) int64 {
+	switch field.Kind() {
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		pk = int64(field.Uint())
+		return int64(field.Uint())
 	default:
-		return "", 0, fmt.Errorf("meddler found field %s which is marked as the primary key, but is not an integer type", name)
+		return field.Int()
 	}
+}
 
-	return name, pk, nil
+// setInteger sets an integer field of any kind to n.
+func setInteger(field reflect.Value, n int64) {
+	switch field.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		field.SetUint(uint64(n))
+	default:
+		field.SetInt(n)
+	}
+}
+
+// pkIsZero reports whether all of the primary key fields are zero.
+func pkIsZero(fields []reflect.Value) bool {
+	for _, field := range fields {
+		if !field.IsZero() {
+			return false
+		}
+	}
+	return true
+}
+
+// pkValue returns a primary key value in a form suitable for a database
+// driver: integers are converted to int64 and byte arrays to byte slices.
+func pkValue(val reflect.Value) interface{} {
+	if val.Type().Implements(valuerType) {
+		return val.Interface()
+	}
+	if val.CanAddr() && val.Addr().Type().Implements(valuerType) {
+		return val.Addr().Interface()
+	}
+	switch val.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return val.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(val.Uint())
+	case reflect.Array:
+		if val.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, val.Len())
+			reflect.Copy(reflect.ValueOf(b), val)
+			return b
+		}
+	}
+	return val.Interface()
+}
+
+// isIntegerKind reports whether values of type t are integers.
+func isIntegerKind(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// PrimaryKey returns the names and values of the primary key fields, in
+// the order they appear in the struct. The names are empty if there is no
+// primary key field marked. Integer keys are returned as int64 and
+// [16]byte keys as a []byte.
+func (d *Database) PrimaryKey(src interface{}) (names []string, pks []interface{}, err error) {
+	names, fields, err := d.primaryKeyFields(src)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, field := range fields {
+		pks = append(pks, pkValue(field))
+	}
+
+	return names, pks, nil
 }
 
 // PrimaryKey using the Default Database type
-func PrimaryKey(src interface{}) (name string, pk int64, err error) {
+func PrimaryKey(src interface{}) (names []string, pks []interface{}, err error) {
 	return Default.PrimaryKey(src)
 }
 
-// SetPrimaryKey sets the primary key field to the given int value.
-func (d *Database) SetPrimaryKey(src interface{}, pk int64) error {
-	data, err := getFields(reflect.TypeOf(src))
+// SetPrimaryKey sets the primary key field to the given value. Integer
+// values can be stored in any integer field, strings and byte slices in
+// string fields, and byte slices or UUID strings in [16]byte fields. Fields
+// that implement sql.Scanner are given the value to scan.
+// The struct must have a single primary key field.
+func (d *Database) SetPrimaryKey(src interface{}, pk interface{}) error {
+	names, fields, err := d.primaryKeyFields(src)
 	if err != nil {
 		return err
 	}
 
-	if data.pk == "" {
-		return fmt.Errorf("meddler.SetPrimaryKey: no primary key field found")
+	if len(names) == 0 {
+		return &opErr{op: "meddler.SetPrimaryKey", err: ErrNoPrimaryKey}
+	}
+	if len(names) > 1 {
+		return fmt.Errorf("meddler.SetPrimaryKey: composite primary key (%s) cannot be set from a single value", strings.Join(names, ","))
 	}
 
-	field := reflect.ValueOf(src).Elem().Field(data.fields[data.pk].index)
-	switch field.Type().Kind() {
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		field.SetInt(pk)
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		field.SetUint(uint64(pk))
-	default:
-		return fmt.Errorf("meddler found field %s which is marked as the primary key, but is not an integer type", data.pk)
+	if err := setPrimaryKey(fields[0], pk); err != nil {
+		return &ColumnError{Column: names[0], Op: "meddler.SetPrimaryKey", Err: err}
 	}
 
 	return nil
 }
 
 // SetPrimaryKey using the Default Database type
-func SetPrimaryKey(src interface{}, pk int64) error {
+func SetPrimaryKey(src interface{}, pk interface{}) error {
 	return Default.SetPrimaryKey(src, pk)
 }
 
+func setPrimaryKey(field reflect.Value, pk interface{}) error {
+	// let types that know how to read database values do it themselves
+	if scanner, ok := field.Addr().Interface().(sql.Scanner); ok {
+		return scanner.Scan(pk)
+	}
+
+	if pk == nil {
+		return fmt.Errorf("cannot store nil in primary key of type %v", field.Type())
+	}
+	val := reflect.ValueOf(pk)
+
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch val.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			field.SetInt(val.Int())
+			return nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			field.SetInt(int64(val.Uint()))
+			return nil
+		}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		switch val.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			field.SetUint(uint64(val.Int()))
+			return nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			field.SetUint(val.Uint())
+			return nil
+		}
+
+	case reflect.String:
+		switch v := pk.(type) {
+		case string:
+			field.SetString(v)
+			return nil
+		case []byte:
+			field.SetString(string(v))
+			return nil
+		}
+
+	case reflect.Array:
+		var raw []byte
+		switch v := pk.(type) {
+		case []byte:
+			raw = v
+		case string:
+			raw = []byte(v)
+		}
+		if raw != nil && field.Type().Elem().Kind() == reflect.Uint8 {
+			if len(raw) != field.Len() {
+				// databases often return UUIDs in their text form
+				raw = parseUUID(raw)
+			}
+			if len(raw) == field.Len() {
+				reflect.Copy(field, reflect.ValueOf(raw))
+				return nil
+			}
+		}
+	}
+
+	if val.Type().AssignableTo(field.Type()) {
+		field.Set(val)
+		return nil
+	}
+
+	return fmt.Errorf("cannot store value of type %T in primary key of type %v", pk, field.Type())
+}
+
+// parseUUID decodes a UUID in its text form, e.g.
+// 6ba7b810-9dad-11d1-80b4-00c04fd430c8, returning nil if it is malformed.
+func parseUUID(text []byte) []byte {
+	if len(text) != 36 {
+		return nil
+	}
+	hexDigits := make([]byte, 0, 32)
+	for i, c := range text {
+		if i == 8 || i == 13 || i == 18 || i == 23 {
+			if c != '-' {
+				return nil
+			}
+			continue
+		}
+		hexDigits = append(hexDigits, c)
+	}
+	raw := make([]byte, 16)
+	if _, err := hex.Decode(raw, hexDigits); err != nil {
+		return nil
+	}
+	return raw
+}
+
 // Values returns a list of PreWrite processed values suitable for
 // use in an INSERT or UPDATE query. If includePk is false, the primary
 // key field is omitted. The columns used are the same ones (in the same
@@ -295,7 +1086,7 @@ func Values(src interface{}, includePk bool) ([]interface{}, error) {
 // use in an INSERT or UPDATE query. The columns used are the same ones (in
 // the same order) as specified in the columns argument.
 func (d *Database) SomeValues(src interface{}, columns []string) ([]interface{}, error) {
-	data, err := getFields(reflect.TypeOf(src))
+	data, err := d.getFields(reflect.TypeOf(src))
 	if err != nil {
 		return nil, err
 	}
@@ -306,17 +1097,23 @@ func (d *Database) SomeValues(src interface{}, columns []string) ([]interface{},
 		field, present := data.fields[name]
 		if !present {
 			// write null to the database
+			if err := d.unmatched("meddler.SomeValues", name); err != nil {
+				return nil, err
+			}
 			values = append(values, nil)
+			continue
+		}
 
-			if Debug {
-				log.Printf("meddler.SomeValues: column [%s] not found in struct", name)
-			}
+		fieldVal, ok := fieldByIndexNoAlloc(structVal, field.index)
+		if !ok {
+			// the field is in a nil embedded struct, so write null
+			values = append(values, nil)
 			continue
 		}
 
-		saveVal, err := field.meddler.PreWrite(structVal.Field(field.index).Interface())
+		saveVal, err := field.meddler.PreWrite(fieldVal.Interface())
 		if err != nil {
-			return nil, fmt.Errorf("meddler.SomeValues: PreWrite error on column [%s]: %v", name, err)
+			return nil, &ColumnError{Column: name, Op: "meddler.SomeValues", Err: err}
 		}
 		values = append(values, saveVal)
 	}
@@ -332,14 +1129,14 @@ func SomeValues(src interface{}, columns []string) ([]interface{}, error) {
 // Placeholders returns a list of placeholders suitable for an INSERT or UPDATE query.
 // If includePk is false, the primary key field is omitted.
 func (d *Database) Placeholders(src interface{}, includePk bool) ([]string, error) {
-	data, err := getFields(reflect.TypeOf(src))
+	data, err := d.getFields(reflect.TypeOf(src))
 	if err != nil {
 		return nil, err
 	}
 
 	var placeholders []string
 	for _, name := range data.columns {
-		if !includePk && name == data.pk {
+		if !includePk && data.fields[name].primaryKey {
 			continue
 		}
 		ph := d.placeholder(len(placeholders) + 1)
@@ -397,6 +1194,15 @@ func (d *Database) scanRow(data *structData, rows *sql.Rows, dst interface{}, co
 		return err
 	}
 
+	// remember what was loaded for UpdateChanged
+	if err := d.takeSnapshot(dst, nil); err != nil {
+		return err
+	}
+
+	if err := afterLoad(dst); err != nil {
+		return err
+	}
+
 	return rows.Err()
 }
 
@@ -405,29 +1211,36 @@ func (d *Database) scanRow(data *structData, rows *sql.Rows, dst interface{}, co
 // the Scan is performed, the same values should be handed to
 // WriteTargets to finalize the values and record them in the struct.
 func (d *Database) Targets(dst interface{}, columns []string) ([]interface{}, error) {
-	data, err := getFields(reflect.TypeOf(dst))
+	data, err := d.getFields(reflect.TypeOf(dst))
 	if err != nil {
 		return nil, err
 	}
 
 	structVal := reflect.ValueOf(dst).Elem()
 
+	if d.Unmatched == UnmatchedStrict {
+		for _, name := range data.columns {
+			if !containsColumn(columns, name) {
+				return nil, &ColumnError{Column: name, Op: "meddler.Targets", Err: ErrMissingColumn}
+			}
+		}
+	}
+
 	var targets []interface{}
 	for _, name := range columns {
 		if field, present := data.fields[name]; present {
-			fieldAddr := structVal.Field(field.index).Addr().Interface()
+			fieldAddr := fieldByIndex(structVal, field.index).Addr().Interface()
 			scanTarget, err := field.meddler.PreRead(fieldAddr)
 			if err != nil {
-				return nil, fmt.Errorf("meddler.Targets: PreRead error on column %s: %v", name, err)
+				return nil, &ColumnError{Column: name, Op: "meddler.Targets", Err: err}
 			}
 			targets = append(targets, scanTarget)
 		} else {
 			// no destination, so throw this away
-			targets = append(targets, new(interface{}))
-
-			if Debug {
-				log.Printf("meddler.Targets: column [%s] not found in struct", name)
+			if err := d.unmatched("meddler.Targets", name); err != nil {
+				return nil, err
 			}
+			targets = append(targets, new(interface{}))
 		}
 	}
 
@@ -448,7 +1261,7 @@ func (d *Database) WriteTargets(dst interface{}, columns []string, targets []int
 			len(columns), len(targets))
 	}
 
-	data, err := getFields(reflect.TypeOf(dst))
+	data, err := d.getFields(reflect.TypeOf(dst))
 	if err != nil {
 		return err
 	}
@@ -456,15 +1269,15 @@ func (d *Database) WriteTargets(dst interface{}, columns []string, targets []int
 
 	for i, name := range columns {
 		if field, present := data.fields[name]; present {
-			fieldAddr := structVal.Field(field.index).Addr().Interface()
+			fieldAddr := fieldByIndex(structVal, field.index).Addr().Interface()
 			err := field.meddler.PostRead(fieldAddr, targets[i])
 			if err != nil {
-				return fmt.Errorf("meddler.WriteTargets: PostRead error on column [%s]: %v", name, err)
+				return &ColumnError{Column: name, Op: "meddler.WriteTargets", Err: err}
 			}
 		} else {
 			// not destination, so throw this away
-			if Debug {
-				log.Printf("meddler.WriteTargets: column [%s] not found in struct", name)
+			if err := d.unmatched("meddler.WriteTargets", name); err != nil {
+				return err
 			}
 		}
 	}
@@ -480,9 +1293,18 @@ func WriteTargets(dst interface{}, columns []string, targets []interface{}) erro
 // Scan scans a single sql result row into a struct.
 // It leaves rows ready to be scanned again for the next row.
 // Returns sql.ErrNoRows if there is no data to read.
-func (d *Database) Scan(rows *sql.Rows, dst interface{}) error {
+//
+// dst may also be a map[string]interface{} (or a pointer to one), which
+// gets an entry for every column, or a pointer to a scalar such as an
+// int64 or a string for a single-column result. opts select the meddlers
+// to use for those, by column name.
+func (d *Database) Scan(rows *sql.Rows, dst interface{}, opts ...ScanOptions) error {
+	if scanned, err := scanOther(rows, dst, scanMeddlers(opts)); scanned {
+		return err
+	}
+
 	// get the list of struct fields
-	data, err := getFields(reflect.TypeOf(dst))
+	data, err := d.getFields(reflect.TypeOf(dst))
 	if err != nil {
 		return err
 	}
@@ -497,18 +1319,19 @@ func (d *Database) Scan(rows *sql.Rows, dst interface{}) error {
 }
 
 // Scan using the Default Database type
-func Scan(rows *sql.Rows, dst interface{}) error {
-	return Default.Scan(rows, dst)
+func Scan(rows *sql.Rows, dst interface{}, opts ...ScanOptions) error {
+	return Default.Scan(rows, dst, opts...)
 }
 
 // ScanRow scans a single sql result row into a struct.
 // It reads exactly one result row and closes rows when finished.
 // Returns sql.ErrNoRows if there is no result row.
-func (d *Database) ScanRow(rows *sql.Rows, dst interface{}) error {
+// dst and opts are as for Scan.
+func (d *Database) ScanRow(rows *sql.Rows, dst interface{}, opts ...ScanOptions) error {
 	// make sure we always close rows
 	defer rows.Close()
 
-	if err := d.Scan(rows, dst); err != nil {
+	if err := d.Scan(rows, dst, opts...); err != nil {
 		return err
 	}
 	if err := rows.Close(); err != nil {
@@ -519,15 +1342,18 @@ func (d *Database) ScanRow(rows *sql.Rows, dst interface{}) error {
 }
 
 // ScanRow using the Default Database type
-func ScanRow(rows *sql.Rows, dst interface{}) error {
-	return Default.ScanRow(rows, dst)
+func ScanRow(rows *sql.Rows, dst interface{}, opts ...ScanOptions) error {
+	return Default.ScanRow(rows, dst, opts...)
 }
 
 // ScanAll scans all sql result rows into a slice of structs.
 // It reads all rows and closes rows when finished.
 // dst should be a pointer to a slice of the appropriate type.
 // The new results will be appended to any existing data in dst.
-func (d *Database) ScanAll(rows *sql.Rows, dst interface{}) error {
+// Besides struct pointers, the elements may be map[string]interface{}
+// values or scalars (or pointers to scalars, which are nil for NULL),
+// with opts as for Scan.
+func (d *Database) ScanAll(rows *sql.Rows, dst interface{}, opts ...ScanOptions) error {
 	// make sure we always close rows
 	defer rows.Close()
 
@@ -540,6 +1366,9 @@ func (d *Database) ScanAll(rows *sql.Rows, dst interface{}) error {
 	if sliceVal.Kind() != reflect.Slice {
 		return fmt.Errorf("ScanAll called with pointer to non-slice: %T", dst)
 	}
+	if scanned, err := scanAllOther(rows, sliceVal, scanMeddlers(opts)); scanned {
+		return err
+	}
 	ptrType := sliceVal.Type().Elem()
 	if ptrType.Kind() != reflect.Ptr {
 		return fmt.Errorf("ScanAll expects element to be pointers, found %T", dst)
@@ -550,7 +1379,7 @@ func (d *Database) ScanAll(rows *sql.Rows, dst interface{}) error {
 	}
 
 	// get the list of struct fields
-	data, err := getFields(ptrType)
+	data, err := d.getFields(ptrType)
 	if err != nil {
 		return err
 	}
@@ -581,6 +1410,6 @@ func (d *Database) ScanAll(rows *sql.Rows, dst interface{}) error {
 }
 
 // ScanAll using the Default Database type
-func ScanAll(rows *sql.Rows, dst interface{}) error {
-	return Default.ScanAll(rows, dst)
+func ScanAll(rows *sql.Rows, dst interface{}, opts ...ScanOptions) error {
+	return Default.ScanAll(rows, dst, opts...)
 }
@@ -52,6 +52,22 @@ type UintPerson struct {
 	Height    *int       `meddler:"height"`
 }
 
+type Label struct {
+	Code  string `meddler:"code,pk"`
+	Title string `meddler:"title"`
+}
+
+type Token struct {
+	ID    [16]byte `meddler:"id,pk"`
+	Owner string   `meddler:"owner"`
+}
+
+type Membership struct {
+	PersonID int64  `meddler:"person_id,pk"`
+	GroupID  int64  `meddler:"group_id,pk"`
+	Role     string `meddler:"role"`
+}
+
 const schema1 = `create table person (
 	id integer primary key,
 	name text not null,
@@ -69,6 +85,41 @@ const schema2 = `create table item (
 	stuffz blob not null
 )`
 
+const schema3 = `create table label (
+	code text primary key,
+	title text not null
+)`
+
+const schema4 = `create table token (
+	id blob primary key,
+	owner text not null
+)`
+
+const schema5 = `create table membership (
+	person_id integer not null,
+	group_id integer not null,
+	role text not null,
+	primary key (person_id, group_id)
+)`
+
+const schema6 = `create table article (
+	id integer primary key,
+	title text not null,
+	created datetime not null,
+	updated datetime,
+	audit_by text,
+	audit_note text
+)`
+
+const schema7 = `create table document (
+	id integer primary key,
+	title text not null,
+	version integer not null,
+	created datetime,
+	updated datetime,
+	deleted datetime
+)`
+
 var aliceHeight int = 65
 var alice = &Person{
 	Name:      "Alice",
@@ -103,6 +154,27 @@ func setup() {
 	if _, err = db.Exec(schema2); err != nil {
 		panic("error creating item table: " + err.Error())
 	}
+	if _, err = db.Exec(schema3); err != nil {
+		panic("error creating label table: " + err.Error())
+	}
+	if _, err = db.Exec(schema4); err != nil {
+		panic("error creating token table: " + err.Error())
+	}
+	if _, err = db.Exec(schema5); err != nil {
+		panic("error creating membership table: " + err.Error())
+	}
+	if _, err = db.Exec(schema6); err != nil {
+		panic("error creating article table: " + err.Error())
+	}
+	if _, err = db.Exec(schema7); err != nil {
+		panic("error creating document table: " + err.Error())
+	}
+}
+
+// registered returns the meddler registered globally with the given name.
+func registered(name string) Meddler {
+	m, _ := registry.lookup(name)
+	return m
 }
 
 func structFieldEqual(t *testing.T, elt *structField, ref *structField) {
@@ -116,7 +188,7 @@ func structFieldEqual(t *testing.T, elt *structField, ref *structField) {
 	if elt.primaryKey != ref.primaryKey {
 		t.Errorf("Column %s primaryKey found as %v", ref.column, elt.primaryKey)
 	}
-	if elt.index != ref.index {
+	if !reflect.DeepEqual(elt.index, ref.index) {
 		t.Errorf("Column %s index found as %v", ref.column, elt.index)
 	}
 	if elt.meddler != ref.meddler {
@@ -125,7 +197,7 @@ func structFieldEqual(t *testing.T, elt *structField, ref *structField) {
 }
 
 func TestGetFields(t *testing.T) {
-	data, err := getFields(reflect.TypeOf((*Person)(nil)))
+	data, err := Default.getFields(reflect.TypeOf((*Person)(nil)))
 	if err != nil {
 		t.Errorf("Error in getFields: %v", err)
 		return
@@ -135,14 +207,88 @@ func TestGetFields(t *testing.T) {
 	if len(data.fields) != 8 || len(data.columns) != 8 {
 		t.Errorf("Found %d/%d fields, expected 8", len(data.fields), len(data.columns))
 	}
-	structFieldEqual(t, data.fields[data.columns[0]], &structField{"id", 0, true, registry["identity"]})
-	structFieldEqual(t, data.fields[data.columns[1]], &structField{"name", 1, false, registry["identity"]})
-	structFieldEqual(t, data.fields[data.columns[2]], &structField{"Email", 3, false, registry["identity"]})
-	structFieldEqual(t, data.fields[data.columns[3]], &structField{"Age", 5, false, registry["zeroisnull"]})
-	structFieldEqual(t, data.fields[data.columns[4]], &structField{"opened", 6, false, registry["utctime"]})
-	structFieldEqual(t, data.fields[data.columns[5]], &structField{"closed", 7, false, registry["utctimez"]})
-	structFieldEqual(t, data.fields[data.columns[6]], &structField{"updated", 8, false, registry["localtime"]})
-	structFieldEqual(t, data.fields[data.columns[7]], &structField{"height", 9, false, registry["identity"]})
+	structFieldEqual(t, data.fields[data.columns[0]], &structField{column: "id", index: []int{0}, primaryKey: true, meddler: registered("identity")})
+	structFieldEqual(t, data.fields[data.columns[1]], &structField{column: "name", index: []int{1}, meddler: registered("identity")})
+	structFieldEqual(t, data.fields[data.columns[2]], &structField{column: "Email", index: []int{3}, meddler: registered("identity")})
+	structFieldEqual(t, data.fields[data.columns[3]], &structField{column: "Age", index: []int{5}, meddler: registered("zeroisnull")})
+	structFieldEqual(t, data.fields[data.columns[4]], &structField{column: "opened", index: []int{6}, meddler: registered("utctime")})
+	structFieldEqual(t, data.fields[data.columns[5]], &structField{column: "closed", index: []int{7}, meddler: registered("utctimez")})
+	structFieldEqual(t, data.fields[data.columns[6]], &structField{column: "updated", index: []int{8}, meddler: registered("localtime")})
+	structFieldEqual(t, data.fields[data.columns[7]], &structField{column: "height", index: []int{9}, meddler: registered("identity")})
+}
+
+type Timestamps struct {
+	Created time.Time `meddler:"created,utctime"`
+	Updated time.Time `meddler:"updated,utctimez"`
+}
+
+type AuditInfo struct {
+	By   string `meddler:"by,zeroisnull"`
+	Note string `meddler:"note,zeroisnull"`
+}
+
+type Article struct {
+	ID    int64  `meddler:"id,pk"`
+	Title string `meddler:"title"`
+	Timestamps
+	*AuditInfo `meddler:",prefix=audit_"`
+}
+
+func TestGetFieldsEmbedded(t *testing.T) {
+	data, err := Default.getFields(reflect.TypeOf((*Article)(nil)))
+	if err != nil {
+		t.Errorf("Error in getFields: %v", err)
+		return
+	}
+
+	expected := []string{"id", "title", "created", "updated", "audit_by", "audit_note"}
+	if !reflect.DeepEqual(data.columns, expected) {
+		t.Errorf("Expected columns %v, found %v", expected, data.columns)
+	}
+	structFieldEqual(t, data.fields["created"], &structField{column: "created", index: []int{2, 0}, meddler: registered("utctime")})
+	structFieldEqual(t, data.fields["audit_note"], &structField{column: "audit_note", index: []int{3, 1}, meddler: registered("zeroisnull")})
+
+	// a shallower field shadows a deeper one
+	type Shadow struct {
+		Timestamps
+		Created string `meddler:"created"`
+	}
+	data, err = Default.getFields(reflect.TypeOf((*Shadow)(nil)))
+	if err != nil {
+		t.Errorf("Error in getFields: %v", err)
+		return
+	}
+	if len(data.columns) != 2 || !reflect.DeepEqual(data.fields["created"].index, []int{1}) {
+		t.Errorf("Expected created to come from the outer struct, found %v", data.fields["created"])
+	}
+
+	// fields at the same depth conflict
+	type Conflict struct {
+		AuditInfo
+		Inner
+	}
+	if _, err := Default.getFields(reflect.TypeOf((*Conflict)(nil))); err == nil {
+		t.Errorf("Expected error for conflicting embedded fields, got none")
+	}
+
+	// but a shallower field resolves the conflict
+	type Resolved struct {
+		AuditInfo
+		Inner
+		By string `meddler:"by"`
+	}
+	data, err = Default.getFields(reflect.TypeOf((*Resolved)(nil)))
+	if err != nil {
+		t.Errorf("Error in getFields: %v", err)
+		return
+	}
+	if !reflect.DeepEqual(data.fields["by"].index, []int{2}) {
+		t.Errorf("Expected by to come from the outer struct, found %v", data.fields["by"])
+	}
+}
+
+type Inner struct {
+	By string `meddler:"by"`
 }
 
 func personEqual(t *testing.T, elt *Person, ref *Person) {
@@ -265,31 +411,135 @@ func TestColumnsQuoted(t *testing.T) {
 	}
 }
 
+type SqlxPerson struct {
+	ID      int64     `db:"id,pk" json:"person_id"`
+	Name    string    `db:"name" json:"full_name,omitempty"`
+	Email   string    `json:"email"`
+	Opened  time.Time `db:"opened,utctime"`
+	Comment string    `json:"-"`
+}
+
+func TestTagName(t *testing.T) {
+	sqlx := *SQLite
+	sqlx.TagName = "db"
+	names, err := sqlx.Columns(&SqlxPerson{}, true)
+	if err != nil {
+		t.Errorf("Error getting Columns: %v", err)
+	}
+	expected := []string{"id", "name", "Email", "opened", "Comment"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected columns %v with db tags, found %v", expected, names)
+	}
+	if pk, _, err := sqlx.PrimaryKey(&SqlxPerson{ID: 3}); err != nil || len(pk) != 1 || pk[0] != "id" {
+		t.Errorf("Expected primary key id with db tags, found %v (%v)", pk, err)
+	}
+
+	// json tags fill in for missing db tags, and the cache keeps the
+	// two configurations apart
+	withJSON := sqlx
+	withJSON.JSONTagFallback = true
+	names, err = withJSON.Columns(&SqlxPerson{}, true)
+	if err != nil {
+		t.Errorf("Error getting Columns: %v", err)
+	}
+	expected = []string{"id", "name", "email", "opened"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected columns %v with json fallback, found %v", expected, names)
+	}
+	names, err = SQLite.Columns(&SqlxPerson{}, true)
+	if err != nil {
+		t.Errorf("Error getting Columns: %v", err)
+	}
+	expected = []string{"ID", "Name", "Email", "Opened", "Comment"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected field names as columns without db tags, found %v", names)
+	}
+}
+
 func TestPrimaryKey(t *testing.T) {
 	p := new(Person)
 	p.ID = 56
-	name, val, err := PrimaryKey(p)
+	names, vals, err := PrimaryKey(p)
 	if err != nil {
 		t.Errorf("Error getting PrimaryKey: %v", err)
 	}
-	if name != "id" {
-		t.Errorf("Expected pk name to be id, found %s", name)
+	if len(names) != 1 || names[0] != "id" {
+		t.Errorf("Expected pk names to be [id], found %v", names)
 	}
-	if val != 56 {
-		t.Errorf("Expected pk value to be 56, found %d", val)
+	if len(vals) != 1 || vals[0] != int64(56) {
+		t.Errorf("Expected pk values to be [56], found %v", vals)
 	}
 
 	p2 := new(UintPerson)
 	p2.ID = 56
-	name, val, err = PrimaryKey(p2)
+	names, vals, err = PrimaryKey(p2)
+	if err != nil {
+		t.Errorf("Error getting PrimaryKey: %v", err)
+	}
+	if len(names) != 1 || names[0] != "id" {
+		t.Errorf("Expected pk names to be [id], found %v", names)
+	}
+	if len(vals) != 1 || vals[0] != int64(56) {
+		t.Errorf("Expected pk values to be [56], found %v", vals)
+	}
+}
+
+func TestPrimaryKeyNonInteger(t *testing.T) {
+	names, vals, err := PrimaryKey(&Label{Code: "red"})
+	if err != nil {
+		t.Errorf("Error getting PrimaryKey: %v", err)
+	}
+	if len(names) != 1 || names[0] != "code" || vals[0] != "red" {
+		t.Errorf("Expected pk code=red, found %v=%v", names, vals)
+	}
+
+	tok := &Token{ID: [16]byte{1, 2, 3}}
+	_, vals, err = PrimaryKey(tok)
+	if err != nil {
+		t.Errorf("Error getting PrimaryKey: %v", err)
+	}
+	if b, ok := vals[0].([]byte); !ok || len(b) != 16 || b[0] != 1 || b[2] != 3 {
+		t.Errorf("Expected pk to be a 16-byte slice, found %#v", vals[0])
+	}
+
+	if err := SetPrimaryKey(tok, "6ba7b810-9dad-11d1-80b4-00c04fd430c8"); err != nil {
+		t.Errorf("Error in SetPrimaryKey: %v", err)
+	}
+	if tok.ID[0] != 0x6b || tok.ID[15] != 0xc8 {
+		t.Errorf("Expected id to be parsed from UUID text, found %x", tok.ID)
+	}
+
+	type FloatKey struct {
+		ID float64 `meddler:"id,pk"`
+	}
+	if _, _, err := PrimaryKey(&FloatKey{}); err == nil {
+		t.Errorf("Expected error for float primary key, got none")
+	}
+}
+
+func TestPrimaryKeyComposite(t *testing.T) {
+	m := &Membership{PersonID: 3, GroupID: 7, Role: "admin"}
+	names, vals, err := PrimaryKey(m)
 	if err != nil {
 		t.Errorf("Error getting PrimaryKey: %v", err)
 	}
-	if name != "id" {
-		t.Errorf("Expected pk name to be id, found %s", name)
+	if len(names) != 2 || names[0] != "person_id" || names[1] != "group_id" {
+		t.Errorf("Expected pk names to be [person_id group_id], found %v", names)
+	}
+	if len(vals) != 2 || vals[0] != int64(3) || vals[1] != int64(7) {
+		t.Errorf("Expected pk values to be [3 7], found %v", vals)
+	}
+
+	cols, err := Columns(m, false)
+	if err != nil {
+		t.Errorf("Error getting Columns: %v", err)
 	}
-	if val != 56 {
-		t.Errorf("Expected pk value to be 56, found %d", val)
+	if len(cols) != 1 || cols[0] != "role" {
+		t.Errorf("Expected non-pk columns to be [role], found %v", cols)
+	}
+
+	if err := SetPrimaryKey(m, 1); err == nil {
+		t.Errorf("Expected error setting a composite primary key, got none")
 	}
 }
 
@@ -475,3 +725,57 @@ func TestThrowAway(t *testing.T) {
 	Debug = true
 	db.Exec("delete from person")
 }
+
+type messageLog []string
+
+func (m *messageLog) Printf(format string, v ...interface{}) {
+	*m = append(*m, fmt.Sprintf(format, v...))
+}
+
+func TestUnmatched(t *testing.T) {
+	once.Do(setup)
+	insertAliceBob(t)
+
+	var messages messageLog
+	d := *SQLite
+	d.Unmatched = UnmatchedLog
+	d.Logger = &messages
+	if err := d.QueryRow(db, new(HalfPerson), "select * from person where id = 1"); err != nil {
+		t.Errorf("QueryRow error: %v", err)
+	}
+	expected := "meddler.Targets: column [name] not found in struct"
+	if len(messages) == 0 || messages[0] != expected {
+		t.Errorf("Expected the first message to be %q, found %q", expected, messages)
+	}
+
+	messages = nil
+	d.Unmatched = UnmatchedSilent
+	if err := d.QueryRow(db, new(HalfPerson), "select * from person where id = 1"); err != nil {
+		t.Errorf("QueryRow error: %v", err)
+	}
+	if len(messages) != 0 {
+		t.Errorf("Expected no messages in silent mode, found %q", messages)
+	}
+
+	// strict mode rejects extra columns and missing fields
+	d.Unmatched = UnmatchedStrict
+	err := d.QueryRow(db, new(HalfPerson), "select * from person where id = 1")
+	if colErr, ok := err.(*ColumnError); !ok || colErr.Err != ErrUnmatchedColumn || colErr.Column != "name" {
+		t.Errorf("Strict QueryRow with an extra column: want a ColumnError for name, got %v", err)
+	}
+	err = d.QueryRow(db, new(HalfPerson), "select id, Age, closed from person where id = 1")
+	if colErr, ok := err.(*ColumnError); !ok || colErr.Err != ErrMissingColumn || colErr.Column != "updated" {
+		t.Errorf("Strict QueryRow with a missing column: want a ColumnError for updated, got %v", err)
+	}
+	if _, err := d.SomeValues(new(HalfPerson), []string{"id", "name"}); err == nil {
+		t.Errorf("Strict SomeValues with an extra column: want error, got none")
+	}
+	elt := new(Person)
+	if err := d.Load(db, "person", elt, 1); err != nil {
+		t.Errorf("Strict Load error: %v", err)
+	}
+	if len(messages) != 0 {
+		t.Errorf("Expected no messages in strict mode, found %q", messages)
+	}
+	db.Exec("delete from person")
+}
@@ -0,0 +1,197 @@
+package meddler
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+)
+
+// ScanOptions adjusts how Scan, ScanRow, and ScanAll read a result into
+// maps and scalars, which have no struct tags to select meddlers.
+type ScanOptions struct {
+	// Meddlers gives the meddler to use for each named column. Other
+	// columns are read as returned by the driver. For a map entry, the
+	// meddler is given a *interface{}, which the time, json, and gob
+	// meddlers support.
+	Meddlers map[string]Meddler
+}
+
+var mapType = reflect.TypeOf(map[string]interface{}(nil))
+
+// isScalar reports whether a value of type t is scanned as a single column
+// rather than as a struct with one field per column. Pointers to scalars
+// are scalars too, and are set to nil for NULL columns.
+func isScalar(t reflect.Type) bool {
+	if t == mapType {
+		return false
+	}
+	if t.Kind() == reflect.Ptr {
+		return isScalar(t.Elem())
+	}
+	return t.Kind() != reflect.Struct || t == timeType || reflect.PtrTo(t).Implements(scannerType)
+}
+
+// scanMeddlers merges the meddlers of all of the given options, with later
+// options taking precedence.
+func scanMeddlers(opts []ScanOptions) map[string]Meddler {
+	if len(opts) == 1 {
+		return opts[0].Meddlers
+	}
+	meddlers := make(map[string]Meddler)
+	for _, opt := range opts {
+		for name, m := range opt.Meddlers {
+			meddlers[name] = m
+		}
+	}
+	return meddlers
+}
+
+// scanOther scans a single row into dst if it is a map or a pointer to a
+// scalar, and reports whether it was. Struct destinations are left to
+// scanRow.
+func scanOther(rows *sql.Rows, dst interface{}, meddlers map[string]Meddler) (bool, error) {
+	switch dstVal := reflect.ValueOf(dst); {
+	case dstVal.Type() == mapType:
+		if dstVal.IsNil() {
+			return true, fmt.Errorf("meddler.Scan: called with nil map")
+		}
+		return true, scanMapRow(rows, dst.(map[string]interface{}), meddlers)
+
+	case dstVal.Kind() == reflect.Ptr && dstVal.Type().Elem() == mapType:
+		if dstVal.IsNil() {
+			return true, fmt.Errorf("meddler.Scan: called with nil pointer")
+		}
+		m := dstVal.Interface().(*map[string]interface{})
+		if *m == nil {
+			*m = make(map[string]interface{})
+		}
+		return true, scanMapRow(rows, *m, meddlers)
+
+	case dstVal.Kind() == reflect.Ptr && isScalar(dstVal.Type().Elem()):
+		if dstVal.IsNil() {
+			return true, fmt.Errorf("meddler.Scan: called with nil pointer")
+		}
+		return true, scanScalarRow(rows, dst, meddlers)
+	}
+
+	return false, nil
+}
+
+// scanMapRow scans the next row into m, with one entry per column.
+func scanMapRow(rows *sql.Rows, m map[string]interface{}, meddlers map[string]Meddler) error {
+	// check if there is data waiting
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return sql.ErrNoRows
+	}
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	// get a list of targets
+	values := make([]interface{}, len(columns))
+	targets := make([]interface{}, len(columns))
+	for i, name := range columns {
+		values[i] = new(interface{})
+		targets[i] = values[i]
+		if meddler, present := meddlers[name]; present {
+			if targets[i], err = meddler.PreRead(values[i]); err != nil {
+				return &ColumnError{Column: name, Op: "meddler.Scan", Err: err}
+			}
+		}
+	}
+
+	// perform the scan
+	if err := rows.Scan(targets...); err != nil {
+		return err
+	}
+
+	// post-process and copy the target values into the map
+	for i, name := range columns {
+		if meddler, present := meddlers[name]; present {
+			if err := meddler.PostRead(values[i], targets[i]); err != nil {
+				return &ColumnError{Column: name, Op: "meddler.Scan", Err: err}
+			}
+		}
+		m[name] = *values[i].(*interface{})
+	}
+
+	return rows.Err()
+}
+
+// scanScalarRow scans the next row of a single-column result into dst,
+// which must be a pointer.
+func scanScalarRow(rows *sql.Rows, dst interface{}, meddlers map[string]Meddler) error {
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	if len(columns) != 1 {
+		return fmt.Errorf("meddler.Scan: scanning into %T needs a single column, found %d", dst, len(columns))
+	}
+
+	// check if there is data waiting
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return sql.ErrNoRows
+	}
+
+	meddler, present := meddlers[columns[0]]
+	if !present {
+		if err := rows.Scan(dst); err != nil {
+			return err
+		}
+		return rows.Err()
+	}
+
+	target, err := meddler.PreRead(dst)
+	if err != nil {
+		return &ColumnError{Column: columns[0], Op: "meddler.Scan", Err: err}
+	}
+	if err := rows.Scan(target); err != nil {
+		return err
+	}
+	if err := meddler.PostRead(dst, target); err != nil {
+		return &ColumnError{Column: columns[0], Op: "meddler.Scan", Err: err}
+	}
+
+	return rows.Err()
+}
+
+// scanAllOther appends all of the rows to sliceVal if its elements are
+// maps or scalars, and reports whether they were. Slices of struct
+// pointers are left to ScanAll.
+func scanAllOther(rows *sql.Rows, sliceVal reflect.Value, meddlers map[string]Meddler) (bool, error) {
+	eltType := sliceVal.Type().Elem()
+	isMap := eltType == mapType
+	if !isMap && !isScalar(eltType) {
+		return false, nil
+	}
+
+	for {
+		// create a new element and scan it
+		eltVal := reflect.New(eltType)
+		var err error
+		if isMap {
+			eltVal.Elem().Set(reflect.MakeMap(mapType))
+			err = scanMapRow(rows, eltVal.Elem().Interface().(map[string]interface{}), meddlers)
+		} else {
+			err = scanScalarRow(rows, eltVal.Interface(), meddlers)
+		}
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return true, nil
+			}
+			return true, err
+		}
+
+		// add to the result slice
+		sliceVal.Set(reflect.Append(sliceVal, eltVal.Elem()))
+	}
+}
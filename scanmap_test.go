@@ -0,0 +1,115 @@
+package meddler
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func TestScanMap(t *testing.T) {
+	once.Do(setup)
+	insertAliceBob(t)
+
+	// a map gets an entry for every column, with meddlers by column name
+	rows, err := db.Query("select id, name, closed from person where id = 2")
+	if err != nil {
+		t.Fatalf("DB error on query: %v", err)
+	}
+	m := make(map[string]interface{})
+	opts := ScanOptions{Meddlers: map[string]Meddler{"closed": TimeMeddler{ZeroIsNull: true}}}
+	if err := ScanRow(rows, m, opts); err != nil {
+		t.Fatalf("ScanRow error: %v", err)
+	}
+	if m["id"] != int64(2) || m["name"] != "Bob" {
+		t.Errorf("Unexpected map for Bob: %v", m)
+	}
+	if closed, ok := m["closed"].(time.Time); !ok || !closed.IsZero() {
+		t.Errorf("Expected zeroisnull time meddler to give a zero time, found %#v", m["closed"])
+	}
+
+	// a nil map behind a pointer is allocated
+	var p map[string]interface{}
+	if err := QueryRow(db, &p, "select name from person where id = 1"); err != nil {
+		t.Errorf("QueryRow error: %v", err)
+	}
+	if p["name"] != "Alice" {
+		t.Errorf("Expected Alice, found %v", p)
+	}
+
+	var lst []map[string]interface{}
+	opts = ScanOptions{Meddlers: map[string]Meddler{"opened": TimeMeddler{Local: false}}}
+	rows, err = db.Query("select name, opened from person order by id")
+	if err != nil {
+		t.Fatalf("DB error on query: %v", err)
+	}
+	if err := ScanAll(rows, &lst, opts); err != nil {
+		t.Errorf("ScanAll error: %v", err)
+	}
+	if len(lst) != 2 || lst[0]["name"] != "Alice" || lst[1]["name"] != "Bob" {
+		t.Errorf("Expected Alice and Bob, found %v", lst)
+	} else if opened, ok := lst[1]["opened"].(time.Time); !ok || !opened.Equal(when) || opened.Location() != time.UTC {
+		t.Errorf("Expected opened to be %v in UTC, found %#v", when, lst[1]["opened"])
+	}
+
+	var nilMap map[string]interface{}
+	rows, err = db.Query("select name from person")
+	if err != nil {
+		t.Fatalf("DB error on query: %v", err)
+	}
+	if err := ScanRow(rows, nilMap); err == nil {
+		t.Errorf("ScanRow into nil map: want error, got none")
+	}
+	db.Exec("delete from person")
+}
+
+func TestScanScalar(t *testing.T) {
+	once.Do(setup)
+	insertAliceBob(t)
+
+	var count int64
+	if err := QueryRow(db, &count, "select count(*) from person"); err != nil {
+		t.Errorf("QueryRow error: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected a count of 2, found %d", count)
+	}
+
+	var names []string
+	if err := QueryAll(db, &names, "select name from person order by id"); err != nil {
+		t.Errorf("QueryAll error: %v", err)
+	}
+	if len(names) != 2 || names[0] != "Alice" || names[1] != "Bob" {
+		t.Errorf("Expected Alice and Bob, found %v", names)
+	}
+
+	// pointers are nil for NULL columns
+	var heights []*int
+	if err := QueryAll(db, &heights, "select height from person order by id"); err != nil {
+		t.Errorf("QueryAll error: %v", err)
+	}
+	if len(heights) != 2 || heights[0] == nil || *heights[0] != aliceHeight || heights[1] != nil {
+		t.Errorf("Expected Alice's height and nil, found %v", heights)
+	}
+
+	// meddlers apply to scalars too
+	var closed []time.Time
+	rows, err := db.Query("select closed from person order by id")
+	if err != nil {
+		t.Fatalf("DB error on query: %v", err)
+	}
+	if err := ScanAll(rows, &closed, ScanOptions{Meddlers: map[string]Meddler{"closed": registered("utctimez")}}); err != nil {
+		t.Errorf("ScanAll error: %v", err)
+	}
+	if len(closed) != 2 || !closed[0].Equal(when) || !closed[1].IsZero() {
+		t.Errorf("Expected closed times of Alice and Bob, found %v", closed)
+	}
+
+	var name string
+	if err := QueryRow(db, &name, "select name, Email from person"); err == nil {
+		t.Errorf("QueryRow with two columns into a string: want error, got none")
+	}
+	if err := QueryRow(db, &name, "select name from person where id = 3"); err != sql.ErrNoRows {
+		t.Errorf("QueryRow with no rows: want %v, got %v", sql.ErrNoRows, err)
+	}
+	db.Exec("delete from person")
+}
@@ -0,0 +1,238 @@
+package meddler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SelectBuilder builds a SELECT query for the columns of a struct and
+// runs it with QueryAll or QueryRow. It is created by Select:
+//
+//	var people []*Person
+//	err := meddler.PostgreSQL.Select(&Person{}).From("person").
+//		Where("age > ?", 30).OrderBy("name").Limit(10).All(db, &people)
+//
+// Each method changes the builder and returns it, so calls can be chained.
+// A builder is not safe for concurrent use.
+type SelectBuilder struct {
+	d           *Database
+	src         interface{}
+	table       string
+	where       []string
+	args        [][]interface{}
+	orderBy     []string
+	limit       int
+	offset      int
+	hasLimit    bool
+	withDeleted bool
+}
+
+// Select starts a query for the columns of src, which must be a pointer
+// to a struct, e.g. &Person{}. The query reads from the table named by
+// TableName unless From gives another. If the struct has a field tagged
+// softdelete, rows that have been soft deleted are skipped unless
+// WithDeleted is called.
+func (d *Database) Select(src interface{}) *SelectBuilder {
+	return &SelectBuilder{d: d, src: src}
+}
+
+// Select using the Default Database type
+func Select(src interface{}) *SelectBuilder {
+	return Default.Select(src)
+}
+
+// From sets the table to read from.
+func (b *SelectBuilder) From(table string) *SelectBuilder {
+	b.table = table
+	return b
+}
+
+// Where adds a condition that the rows must match, with one ? placeholder
+// for each argument, e.g. Where("age > ? AND name <> ?", 30, "Bob"). The
+// placeholders are rewritten to the style of the Database, except inside
+// quoted strings and names. Several conditions must all match.
+func (b *SelectBuilder) Where(cond string, args ...interface{}) *SelectBuilder {
+	b.where = append(b.where, cond)
+	b.args = append(b.args, args)
+	return b
+}
+
+// OrderBy adds terms to the ORDER BY clause, e.g. OrderBy("age DESC",
+// "name"). They are used as given, so must not hold untrusted input.
+func (b *SelectBuilder) OrderBy(terms ...string) *SelectBuilder {
+	b.orderBy = append(b.orderBy, terms...)
+	return b
+}
+
+// Limit sets the most rows to return.
+func (b *SelectBuilder) Limit(n int) *SelectBuilder {
+	b.limit = n
+	b.hasLimit = true
+	return b
+}
+
+// Offset sets the number of rows to skip.
+func (b *SelectBuilder) Offset(n int) *SelectBuilder {
+	b.offset = n
+	return b
+}
+
+// WithDeleted includes rows that have been soft deleted.
+func (b *SelectBuilder) WithDeleted() *SelectBuilder {
+	b.withDeleted = true
+	return b
+}
+
+// SQL returns the query and its arguments.
+func (b *SelectBuilder) SQL() (string, []interface{}, error) {
+	d := b.d
+	columns, err := d.ColumnsQuoted(b.src, true)
+	if err != nil {
+		return "", nil, err
+	}
+	table := b.table
+	if table == "" {
+		if table, err = d.TableName(b.src); err != nil {
+			return "", nil, err
+		}
+	}
+	if b.limit < 0 || b.offset < 0 {
+		return "", nil, fmt.Errorf("meddler.Select: limit and offset must not be negative")
+	}
+
+	// rewrite the placeholders, numbering them across all of the conditions
+	var conds []string
+	var args []interface{}
+	for i, cond := range b.where {
+		bound, n := d.bindPlaceholders(cond, len(args)+1)
+		if n != len(b.args[i]) {
+			return "", nil, fmt.Errorf("meddler.Select: condition %q has %d placeholders, but %d arguments were given", cond, n, len(b.args[i]))
+		}
+		conds = append(conds, bound)
+		args = append(args, b.args[i]...)
+	}
+	if !b.withDeleted {
+		deleted, err := d.softDeleteColumn(b.src)
+		if err != nil {
+			return "", nil, err
+		}
+		if deleted != "" {
+			conds = append(conds, d.quoted(deleted)+" IS NULL")
+		}
+	}
+
+	q := fmt.Sprintf("SELECT %s FROM %s", columns, d.quoted(table))
+	if len(conds) == 1 {
+		q += " WHERE " + conds[0]
+	} else if len(conds) > 1 {
+		q += " WHERE (" + strings.Join(conds, ") AND (") + ")"
+	}
+
+	orderBy := b.orderBy
+	paged := b.hasLimit || b.offset > 0
+	if len(orderBy) == 0 && paged && d.LimitSyntax == OrderedOffsetFetch {
+		// SQL Server only accepts OFFSET after ORDER BY
+		orderBy = []string{"(SELECT NULL)"}
+	}
+	if len(orderBy) > 0 {
+		q += " ORDER BY " + strings.Join(orderBy, ", ")
+	}
+	if paged {
+		q += d.limitClause(b.hasLimit, b.limit, b.offset)
+	}
+
+	return q, args, nil
+}
+
+// All runs the query and scans the rows into dst, which must be a pointer
+// to a slice of struct pointers.
+func (b *SelectBuilder) All(db DB, dst interface{}) error {
+	return b.AllContext(context.Background(), withContext(db), dst)
+}
+
+// AllContext is like All, but runs the query with the given context.
+func (b *SelectBuilder) AllContext(ctx context.Context, db DBContext, dst interface{}) error {
+	q, args, err := b.SQL()
+	if err != nil {
+		return err
+	}
+	return b.d.QueryAllContext(ctx, db, dst, q, args...)
+}
+
+// One runs the query and scans the first row into dst, which must be a
+// pointer to a struct. Returns sql.ErrNoRows if there is no row.
+func (b *SelectBuilder) One(db DB, dst interface{}) error {
+	return b.OneContext(context.Background(), withContext(db), dst)
+}
+
+// OneContext is like One, but runs the query with the given context.
+func (b *SelectBuilder) OneContext(ctx context.Context, db DBContext, dst interface{}) error {
+	q, args, err := b.SQL()
+	if err != nil {
+		return err
+	}
+	return b.d.QueryRowContext(ctx, db, dst, q, args...)
+}
+
+// limitClause forms the clause limiting the rows returned.
+func (d *Database) limitClause(hasLimit bool, limit, offset int) string {
+	if d.LimitSyntax == OffsetFetch || d.LimitSyntax == OrderedOffsetFetch {
+		q := fmt.Sprintf(" OFFSET %d ROWS", offset)
+		if hasLimit {
+			q += fmt.Sprintf(" FETCH NEXT %d ROWS ONLY", limit)
+		}
+		return q
+	}
+
+	// MySQL and SQLite need a limit to use an offset
+	n := strconv.FormatInt(1<<63-1, 10)
+	if hasLimit {
+		n = strconv.Itoa(limit)
+	}
+	q := " LIMIT " + n
+	if offset > 0 {
+		q += fmt.Sprintf(" OFFSET %d", offset)
+	}
+	return q
+}
+
+// bindPlaceholders rewrites the ? placeholders of a condition into the
+// placeholder style of the Database, numbered starting at first, leaving
+// any inside quoted strings and names alone. It returns the new condition
+// and the number of placeholders found.
+func (d *Database) bindPlaceholders(cond string, first int) (string, int) {
+	closing := map[byte]byte{'\'': '\'', '"': '"', '`': '`'}
+	if d.Quote != "" {
+		closing[d.Quote[0]] = d.Quote[0]
+		if d.QuoteEnd != "" {
+			closing[d.Quote[0]] = d.QuoteEnd[0]
+		}
+	}
+
+	var out bytes.Buffer
+	n := 0
+	for i := 0; i < len(cond); i++ {
+		c := cond[i]
+		if end, ok := closing[c]; ok {
+			// copy the quoted part as it is
+			j := strings.IndexByte(cond[i+1:], end)
+			if j < 0 {
+				out.WriteString(cond[i:])
+				break
+			}
+			out.WriteString(cond[i : i+j+2])
+			i += j + 1
+			continue
+		}
+		if c == '?' {
+			out.WriteString(d.placeholder(first + n))
+			n++
+			continue
+		}
+		out.WriteByte(c)
+	}
+	return out.String(), n
+}
@@ -0,0 +1,146 @@
+package meddler
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func TestSelectSQL(t *testing.T) {
+	tests := []struct {
+		d        *Database
+		b        func(d *Database) *SelectBuilder
+		expected string
+		args     int
+	}{
+		{
+			PostgreSQL,
+			func(d *Database) *SelectBuilder {
+				return d.Select(&Label{}).From("label").Where("code = ? OR title = '?'", "red").Where(`"title" <> ?`, "Blue")
+			},
+			`SELECT "code","title" FROM "label" WHERE (code = $1 OR title = '?') AND ("title" <> $2)`,
+			2,
+		},
+		{
+			MySQL,
+			func(d *Database) *SelectBuilder {
+				return d.Select(&Label{}).From("label").Where("title = ?", "Red").OrderBy("code DESC", "title").Limit(10).Offset(20)
+			},
+			"SELECT `code`,`title` FROM `label` WHERE title = ? ORDER BY code DESC, title LIMIT 10 OFFSET 20",
+			1,
+		},
+		{
+			SQLite,
+			func(d *Database) *SelectBuilder {
+				return d.Select(&Label{}).From("label").Offset(5)
+			},
+			`SELECT "code","title" FROM "label" LIMIT 9223372036854775807 OFFSET 5`,
+			0,
+		},
+		{
+			SQLServer,
+			func(d *Database) *SelectBuilder {
+				return d.Select(&Label{}).From("label").Where("[code] = ? AND title = ?", "red", "Red").Limit(10)
+			},
+			"SELECT [code],[title] FROM [label] WHERE [code] = @p1 AND title = @p2 ORDER BY (SELECT NULL) OFFSET 0 ROWS FETCH NEXT 10 ROWS ONLY",
+			2,
+		},
+		{
+			Oracle,
+			func(d *Database) *SelectBuilder {
+				return d.Select(&ArchivedDocument{}).From("document").OrderBy("id").Offset(10).Limit(5)
+			},
+			`SELECT "id","title","version","deleted" FROM "document" WHERE "deleted" IS NULL ORDER BY id OFFSET 10 ROWS FETCH NEXT 5 ROWS ONLY`,
+			0,
+		},
+		{
+			Oracle,
+			func(d *Database) *SelectBuilder {
+				return d.Select(&Label{}).From("label").Limit(5)
+			},
+			`SELECT "code","title" FROM "label" OFFSET 0 ROWS FETCH NEXT 5 ROWS ONLY`,
+			0,
+		},
+	}
+	for i, test := range tests {
+		q, args, err := test.b(test.d).SQL()
+		if err != nil {
+			t.Errorf("Test %d: SQL error: %v", i, err)
+			continue
+		}
+		if q != test.expected {
+			t.Errorf("Test %d: want %q, got %q", i, test.expected, q)
+		}
+		if len(args) != test.args {
+			t.Errorf("Test %d: want %d arguments, got %d", i, test.args, len(args))
+		}
+	}
+
+	if _, _, err := PostgreSQL.Select(&Label{}).From("label").Where("code = ?").SQL(); err == nil {
+		t.Errorf("Where with too few arguments: want error, got none")
+	}
+	if _, _, err := PostgreSQL.Select(&Label{}).From("label").Limit(-1).SQL(); err == nil {
+		t.Errorf("Negative limit: want error, got none")
+	}
+}
+
+type person struct {
+	ID   int64  `meddler:"id,pk"`
+	Name string `meddler:"name"`
+	Age  int    `meddler:"Age,zeroisnull"`
+}
+
+func TestSelect(t *testing.T) {
+	once.Do(setup)
+	insertAliceBob(t)
+
+	// the table name comes from the struct when From is not called
+	var people []*person
+	if err := SQLite.Select(&person{}).Where("name <> ?", "Carol").OrderBy("id").All(db, &people); err != nil {
+		t.Errorf("All error: %v", err)
+	}
+	if len(people) != 2 || people[0].Name != "Alice" || people[1].Name != "Bob" {
+		t.Errorf("Expected Alice and Bob, found %v", people)
+	}
+
+	elt := new(Person)
+	if err := SQLite.Select(elt).From("person").OrderBy("id DESC").Limit(1).One(db, elt); err != nil {
+		t.Errorf("One error: %v", err)
+	}
+	if elt.Name != "Bob" {
+		t.Errorf("Expected Bob, found %q", elt.Name)
+	}
+	if err := SQLite.Select(elt).From("person").Where("id = ?", 3).One(db, elt); err != sql.ErrNoRows {
+		t.Errorf("One with no rows: want %v, got %v", sql.ErrNoRows, err)
+	}
+
+	// rows that have been soft deleted are skipped
+	d := *SQLite
+	d.Now = func() time.Time { return when }
+	kept := &ArchivedDocument{Title: "Kept", Version: 1}
+	dropped := &ArchivedDocument{Title: "Dropped", Version: 1}
+	for _, doc := range []*ArchivedDocument{kept, dropped} {
+		if err := d.Insert(db, "document", doc); err != nil {
+			t.Fatalf("Insert error: %v", err)
+		}
+	}
+	if err := d.SoftDelete(db, "document", dropped); err != nil {
+		t.Errorf("SoftDelete error: %v", err)
+	}
+	var docs []*ArchivedDocument
+	if err := d.Select(&ArchivedDocument{}).From("document").All(db, &docs); err != nil {
+		t.Errorf("All error: %v", err)
+	}
+	if len(docs) != 1 || docs[0].Title != "Kept" {
+		t.Errorf("Expected only the kept document, found %v", docs)
+	}
+	docs = nil
+	if err := d.Select(&ArchivedDocument{}).From("document").WithDeleted().OrderBy("id").All(db, &docs); err != nil {
+		t.Errorf("All error: %v", err)
+	}
+	if len(docs) != 2 || docs[1].Title != "Dropped" {
+		t.Errorf("Expected both documents with WithDeleted, found %v", docs)
+	}
+	db.Exec("delete from document")
+	db.Exec("delete from person")
+}
@@ -0,0 +1,131 @@
+package meddler
+
+import (
+	"bytes"
+	"reflect"
+	"time"
+)
+
+// Snapshot records the values of a struct as they were last loaded from or
+// written to the database. Embedding it in a struct opts in to change
+// tracking, which lets UpdateChanged write only the columns that changed:
+//
+//	type Person struct {
+//		meddler.Snapshot
+//		ID   int64  `meddler:"id,pk"`
+//		Name string `meddler:"name"`
+//	}
+//
+// The snapshot is taken by Load, QueryRow, QueryAll, and the Scan
+// functions, and refreshed by the functions that write the record.
+type Snapshot struct {
+	values map[string]interface{}
+}
+
+func (s *Snapshot) meddlerSnapshot() *Snapshot {
+	return s
+}
+
+// snapshotter is implemented by structs that embed Snapshot.
+type snapshotter interface {
+	meddlerSnapshot() *Snapshot
+}
+
+var snapshotType = reflect.TypeOf(Snapshot{})
+
+// takeSnapshot records the PreWrite values of the given columns of src,
+// or of all of its columns if columns is nil. It does nothing if src does
+// not embed a Snapshot.
+func (d *Database) takeSnapshot(src interface{}, columns []string) error {
+	tracked, ok := src.(snapshotter)
+	if !ok {
+		return nil
+	}
+	if columns == nil {
+		var err error
+		if columns, err = d.Columns(src, true); err != nil {
+			return err
+		}
+	}
+
+	values, err := d.SomeValues(src, columns)
+	if err != nil {
+		return err
+	}
+
+	snapshot := tracked.meddlerSnapshot()
+	if snapshot == nil {
+		return nil
+	}
+
+	// build a new map, as copies of the struct share the old one
+	snapshotValues := make(map[string]interface{}, len(snapshot.values)+len(columns))
+	for name, val := range snapshot.values {
+		snapshotValues[name] = val
+	}
+	for i, name := range columns {
+		snapshotValues[name] = snapshotValue(values[i])
+	}
+	snapshot.values = snapshotValues
+
+	return nil
+}
+
+// changedColumns returns the columns among names whose PreWrite values
+// differ from the snapshot of src. ok is false if src does not embed a
+// Snapshot or no snapshot has been taken yet.
+func (d *Database) changedColumns(src interface{}, names []string) (changed []string, ok bool, err error) {
+	tracked, isTracked := src.(snapshotter)
+	if !isTracked || tracked.meddlerSnapshot() == nil || tracked.meddlerSnapshot().values == nil {
+		return nil, false, nil
+	}
+	snapshot := tracked.meddlerSnapshot().values
+
+	values, err := d.SomeValues(src, names)
+	if err != nil {
+		return nil, true, err
+	}
+	for i, name := range names {
+		old, present := snapshot[name]
+		if !present || !sameValue(old, snapshotValue(values[i])) {
+			changed = append(changed, name)
+		}
+	}
+
+	return changed, true, nil
+}
+
+// snapshotValue copies a PreWrite value so that later changes made through
+// pointers or to byte slices do not also change the snapshot.
+func snapshotValue(val interface{}) interface{} {
+	v := reflect.ValueOf(val)
+	for v.IsValid() && v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil
+	}
+	if b, ok := v.Interface().([]byte); ok {
+		return append([]byte(nil), b...)
+	}
+	return v.Interface()
+}
+
+// sameValue reports whether two snapshot values would be written to the
+// database as the same value.
+func sameValue(a, b interface{}) bool {
+	switch x := a.(type) {
+	case time.Time:
+		if y, ok := b.(time.Time); ok {
+			return x.Equal(y)
+		}
+	case []byte:
+		if y, ok := b.([]byte); ok {
+			return bytes.Equal(x, y)
+		}
+	}
+	return reflect.DeepEqual(a, b)
+}
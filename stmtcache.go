@@ -0,0 +1,229 @@
+package meddler
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"sync"
+)
+
+// StmtCache is a cache of prepared statements for use as the StmtCache of
+// a Database:
+//
+//	cache := meddler.NewStmtCache(100)
+//	d := *meddler.PostgreSQL
+//	d.StmtCache = cache
+//
+// Statements are keyed by the *sql.DB they were prepared on and the query.
+// When the cache is full, the least recently used statement is removed to
+// make room. It is closed as soon as no query is about to run on it.
+//
+// A *sql.Tx does not reveal the *sql.DB it belongs to, so queries inside a
+// transaction only use the cache when the transaction is wrapped in a
+// StmtTx. Queries on other kinds of DB run without a statement.
+//
+// A StmtCache is safe for concurrent use.
+type StmtCache struct {
+	// OnEvict, if set, is called with each statement the cache removes
+	// to make room for another, before the statement is closed.
+	OnEvict func(query string, stmt *sql.Stmt)
+
+	mu      sync.Mutex
+	size    int
+	order   *list.List // of *stmtEntry, most recently used first
+	entries map[stmtKey]*list.Element
+	stats   StmtCacheStats
+}
+
+// StmtCacheStats counts the lookups of a StmtCache.
+type StmtCacheStats struct {
+	Hits      int64 // statements found in the cache
+	Misses    int64 // statements prepared because they were not found
+	Evictions int64 // statements closed to make room
+}
+
+// StmtTx is a transaction together with the database it was started on,
+// which lets a StmtCache run the queries of the transaction on its cached
+// statements. Pass a *StmtTx to meddler functions in place of the *sql.Tx:
+//
+//	tx, err := db.Begin()
+//	// ...
+//	err = d.Insert(&meddler.StmtTx{Tx: tx, DB: db}, "person", elt)
+type StmtTx struct {
+	*sql.Tx
+	DB *sql.DB
+}
+
+type stmtKey struct {
+	db    *sql.DB
+	query string
+}
+
+type stmtEntry struct {
+	key     stmtKey
+	stmt    *sql.Stmt
+	refs    int  // queries holding the statement until they have started
+	evicted bool // removed from the cache, to be closed when refs is zero
+}
+
+// NewStmtCache returns a StmtCache that holds at most size statements.
+func NewStmtCache(size int) *StmtCache {
+	if size <= 0 {
+		panic("meddler.NewStmtCache: size must be positive")
+	}
+	return &StmtCache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[stmtKey]*list.Element),
+	}
+}
+
+// acquire returns the statement to use for the query on db, preparing it
+// if it is not cached yet, and a function that must be called once the
+// statement has been started. Until then, the statement is not closed even
+// if it is evicted. Within a StmtTx, the cached statement is bound to the
+// transaction. It returns a nil statement for other kinds of DB, so the
+// query runs directly.
+func (c *StmtCache) acquire(ctx context.Context, db DBContext, query string) (*sql.Stmt, func(), error) {
+	var tx *sql.Tx
+	var sqlDB *sql.DB
+	switch elt := db.(type) {
+	case *sql.DB:
+		sqlDB = elt
+	case *StmtTx:
+		tx, sqlDB = elt.Tx, elt.DB
+	case noContext:
+		if nested, ok := elt.DB.(DBContext); ok {
+			return c.acquire(ctx, nested, query)
+		}
+	}
+	if sqlDB == nil {
+		return nil, func() {}, nil
+	}
+
+	entry, err := c.get(ctx, sqlDB, query)
+	if err != nil {
+		return nil, nil, err
+	}
+	release := func() { c.release(entry) }
+	if tx != nil {
+		// the transaction statement keeps the cached one open until the
+		// transaction ends, so it can be released straight away
+		stmt := tx.StmtContext(ctx, entry.stmt)
+		release()
+		return stmt, func() {}, nil
+	}
+	return entry.stmt, release, nil
+}
+
+// get returns the cache entry for the query, preparing the statement on db
+// if needed. The entry is held for the caller until it is released.
+func (c *StmtCache) get(ctx context.Context, db *sql.DB, query string) (*stmtEntry, error) {
+	key := stmtKey{db: db, query: query}
+
+	c.mu.Lock()
+	if elt, present := c.entries[key]; present {
+		c.order.MoveToFront(elt)
+		c.stats.Hits++
+		entry := elt.Value.(*stmtEntry)
+		entry.refs++
+		c.mu.Unlock()
+		return entry, nil
+	}
+	c.stats.Misses++
+	c.mu.Unlock()
+
+	// prepare without holding the lock, so other queries are not held up
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if elt, present := c.entries[key]; present {
+		// another goroutine prepared it first
+		c.order.MoveToFront(elt)
+		entry := elt.Value.(*stmtEntry)
+		entry.refs++
+		c.mu.Unlock()
+		stmt.Close()
+		return entry, nil
+	}
+	entry := &stmtEntry{key: key, stmt: stmt, refs: 1}
+	c.entries[key] = c.order.PushFront(entry)
+	var evicted []*stmtEntry
+	for c.order.Len() > c.size {
+		elt := c.order.Back()
+		old := elt.Value.(*stmtEntry)
+		c.order.Remove(elt)
+		delete(c.entries, old.key)
+		old.evicted = true
+		// hold the statement open until OnEvict has seen it
+		old.refs++
+		c.stats.Evictions++
+		evicted = append(evicted, old)
+	}
+	c.mu.Unlock()
+
+	for _, old := range evicted {
+		if c.OnEvict != nil {
+			c.OnEvict(old.key.query, old.stmt)
+		}
+		c.release(old)
+	}
+	return entry, nil
+}
+
+// release lets go of an entry returned by get, closing its statement if it
+// has been evicted and no other caller holds it.
+func (c *StmtCache) release(entry *stmtEntry) {
+	c.mu.Lock()
+	entry.refs--
+	unused := entry.evicted && entry.refs == 0
+	c.mu.Unlock()
+	if unused {
+		entry.stmt.Close()
+	}
+}
+
+// Stats returns the number of hits, misses, and evictions so far.
+func (c *StmtCache) Stats() StmtCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// Len returns the number of statements in the cache.
+func (c *StmtCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// Close closes all of the cached statements and empties the cache. A
+// statement that a query is about to run is closed once it has started.
+// Close returns the first error from closing a statement. The cache can
+// still be used afterwards, and prepares statements again as they are
+// needed.
+func (c *StmtCache) Close() error {
+	c.mu.Lock()
+	var unused []*stmtEntry
+	for elt := c.order.Front(); elt != nil; elt = elt.Next() {
+		entry := elt.Value.(*stmtEntry)
+		entry.evicted = true
+		if entry.refs == 0 {
+			unused = append(unused, entry)
+		}
+	}
+	c.order = list.New()
+	c.entries = make(map[stmtKey]*list.Element)
+	c.mu.Unlock()
+
+	var first error
+	for _, entry := range unused {
+		if err := entry.stmt.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
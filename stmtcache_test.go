@@ -0,0 +1,167 @@
+package meddler
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestStmtCache(t *testing.T) {
+	once.Do(setup)
+	insertAliceBob(t)
+
+	cache := NewStmtCache(2)
+	var evicted []string
+	cache.OnEvict = func(query string, stmt *sql.Stmt) {
+		evicted = append(evicted, query)
+	}
+	d := *SQLite
+	d.StmtCache = cache
+
+	for i := 0; i < 3; i++ {
+		if err := d.Load(db, "person", new(Person), 1); err != nil {
+			t.Errorf("Load error on Alice: %v", err)
+		}
+	}
+	if stats := cache.Stats(); stats.Hits != 2 || stats.Misses != 1 || stats.Evictions != 0 {
+		t.Errorf("Expected 2 hits and 1 miss, found %+v", stats)
+	}
+
+	// a third query evicts the least recently used one
+	var count int64
+	if err := d.QueryRow(db, &count, "select count(*) from person"); err != nil {
+		t.Errorf("QueryRow error: %v", err)
+	}
+	var names []string
+	if err := d.QueryAll(db, &names, "select name from person order by id"); err != nil {
+		t.Errorf("QueryAll error: %v", err)
+	}
+	if stats := cache.Stats(); stats.Misses != 3 || stats.Evictions != 1 || cache.Len() != 2 {
+		t.Errorf("Expected 3 misses and 1 eviction, found %+v with %d statements", stats, cache.Len())
+	}
+	if len(evicted) != 1 || !strings.HasSuffix(evicted[0], `FROM "person" WHERE "id"=?`) {
+		t.Errorf("Expected the Load query to be evicted, found %q", evicted)
+	}
+
+	// transactions use the cached statements through StmtTx
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin error: %v", err)
+	}
+	count = 0
+	if err := d.QueryRow(&StmtTx{Tx: tx, DB: db}, &count, "select count(*) from person"); err != nil {
+		t.Errorf("QueryRow in a transaction error: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected a count of 2, found %d", count)
+	}
+	if stats := cache.Stats(); stats.Hits != 3 {
+		t.Errorf("Expected the transaction to reuse the statement, found %+v", stats)
+	}
+
+	// a plain transaction runs its queries directly
+	if err := d.Load(tx, "person", new(Person), 2); err != nil {
+		t.Errorf("Load in a transaction error: %v", err)
+	}
+	if stats := cache.Stats(); stats.Hits != 3 || stats.Misses != 3 {
+		t.Errorf("Expected a plain transaction to bypass the cache, found %+v", stats)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Errorf("Rollback error: %v", err)
+	}
+
+	if err := cache.Close(); err != nil {
+		t.Errorf("Close error: %v", err)
+	}
+	if cache.Len() != 0 {
+		t.Errorf("Expected Close to empty the cache, found %d statements", cache.Len())
+	}
+	if err := d.Load(db, "person", new(Person), 1); err != nil {
+		t.Errorf("Load error after Close: %v", err)
+	}
+	if cache.Len() != 1 {
+		t.Errorf("Expected the cache to be used after Close, found %d statements", cache.Len())
+	}
+	cache.Close()
+	db.Exec("delete from person")
+}
+
+func TestStmtCacheInUse(t *testing.T) {
+	once.Do(setup)
+	insertAliceBob(t)
+
+	cache := NewStmtCache(1)
+	stmt, release, err := cache.acquire(context.Background(), db, "select count(*) from person")
+	if err != nil {
+		t.Fatalf("acquire error: %v", err)
+	}
+
+	// evicting a statement that is about to run leaves it open
+	d := *SQLite
+	d.StmtCache = cache
+	if err := d.Load(db, "person", new(Person), 1); err != nil {
+		t.Errorf("Load error: %v", err)
+	}
+	if stats := cache.Stats(); stats.Evictions != 1 {
+		t.Errorf("Expected 1 eviction, found %+v", stats)
+	}
+	var count int64
+	if err := stmt.QueryRow().Scan(&count); err != nil || count != 2 {
+		t.Errorf("Expected the evicted statement to count 2 rows, found %d with error %v", count, err)
+	}
+
+	// and closes it once it has been released
+	release()
+	if err := stmt.QueryRow().Scan(&count); err == nil {
+		t.Errorf("Expected the released statement to be closed")
+	}
+
+	// OnEvict gets an open statement, even if the last query holding it
+	// lets go in the meantime
+	_, release, err = cache.acquire(context.Background(), db, "select count(*) from person")
+	if err != nil {
+		t.Fatalf("acquire error: %v", err)
+	}
+	cache.OnEvict = func(query string, stmt *sql.Stmt) {
+		release()
+		if err := stmt.QueryRow().Scan(&count); err != nil {
+			t.Errorf("Expected OnEvict to get an open statement, found error %v", err)
+		}
+	}
+	if err := d.Load(db, "person", new(Person), 1); err != nil {
+		t.Errorf("Load error: %v", err)
+	}
+	cache.Close()
+	db.Exec("delete from person")
+}
+
+func TestStmtCacheConcurrent(t *testing.T) {
+	once.Do(setup)
+	insertAliceBob(t)
+
+	cache := NewStmtCache(10)
+	d := *SQLite
+	d.StmtCache = cache
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				var count int64
+				if err := d.QueryRow(db, &count, "select count(*) from person"); err != nil {
+					t.Errorf("QueryRow error: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if stats := cache.Stats(); stats.Hits+stats.Misses != 80 || cache.Len() != 1 {
+		t.Errorf("Expected 80 lookups of one statement, found %+v with %d statements", stats, cache.Len())
+	}
+	cache.Close()
+	db.Exec("delete from person")
+}
@@ -0,0 +1,99 @@
+//go:build go1.18
+// +build go1.18
+
+package meddler
+
+import (
+	"context"
+)
+
+// Typed provides the query functions of a Database for a single struct
+// type T. Instead of filling in a dst argument that is checked at run
+// time, they return values of type *T, so mismatched types are caught by
+// the compiler:
+//
+//	people, err := meddler.For[Person](meddler.PostgreSQL).QueryAll(db, "SELECT * FROM person")
+type Typed[T any] struct {
+	d *Database
+}
+
+// For returns the typed query functions of d for the struct type T.
+func For[T any](d *Database) Typed[T] {
+	return Typed[T]{d: d}
+}
+
+// Load loads the record with the given primary key, as Database.Load does.
+// Returns sql.ErrNoRows if not found.
+func (t Typed[T]) Load(db DB, table string, pk ...interface{}) (*T, error) {
+	return t.LoadContext(context.Background(), withContext(db), table, pk...)
+}
+
+// LoadContext is like Load, but runs the query with the given context.
+func (t Typed[T]) LoadContext(ctx context.Context, db DBContext, table string, pk ...interface{}) (*T, error) {
+	dst := new(T)
+	if err := t.d.LoadContext(ctx, db, table, dst, pk...); err != nil {
+		return nil, err
+	}
+	return dst, nil
+}
+
+// QueryRow performs the given query with the given arguments and returns
+// the single row of results. Returns sql.ErrNoRows if there was no result
+// row.
+func (t Typed[T]) QueryRow(db DB, query string, args ...interface{}) (*T, error) {
+	return t.QueryRowContext(context.Background(), withContext(db), query, args...)
+}
+
+// QueryRowContext is like QueryRow, but runs the query with the given context.
+func (t Typed[T]) QueryRowContext(ctx context.Context, db DBContext, query string, args ...interface{}) (*T, error) {
+	dst := new(T)
+	if err := t.d.QueryRowContext(ctx, db, dst, query, args...); err != nil {
+		return nil, err
+	}
+	return dst, nil
+}
+
+// QueryAll performs the given query with the given arguments and returns
+// all of the result rows. An empty result gives an empty slice.
+func (t Typed[T]) QueryAll(db DB, query string, args ...interface{}) ([]*T, error) {
+	return t.QueryAllContext(context.Background(), withContext(db), query, args...)
+}
+
+// QueryAllContext is like QueryAll, but runs the query with the given context.
+func (t Typed[T]) QueryAllContext(ctx context.Context, db DBContext, query string, args ...interface{}) ([]*T, error) {
+	dst := []*T{}
+	if err := t.d.QueryAllContext(ctx, db, &dst, query, args...); err != nil {
+		return nil, err
+	}
+	return dst, nil
+}
+
+// LoadT using the Default Database type
+func LoadT[T any](db DB, table string, pk ...interface{}) (*T, error) {
+	return For[T](Default).Load(db, table, pk...)
+}
+
+// LoadTContext using the Default Database type
+func LoadTContext[T any](ctx context.Context, db DBContext, table string, pk ...interface{}) (*T, error) {
+	return For[T](Default).LoadContext(ctx, db, table, pk...)
+}
+
+// QueryRowT using the Default Database type
+func QueryRowT[T any](db DB, query string, args ...interface{}) (*T, error) {
+	return For[T](Default).QueryRow(db, query, args...)
+}
+
+// QueryRowTContext using the Default Database type
+func QueryRowTContext[T any](ctx context.Context, db DBContext, query string, args ...interface{}) (*T, error) {
+	return For[T](Default).QueryRowContext(ctx, db, query, args...)
+}
+
+// QueryAllT using the Default Database type
+func QueryAllT[T any](db DB, query string, args ...interface{}) ([]*T, error) {
+	return For[T](Default).QueryAll(db, query, args...)
+}
+
+// QueryAllTContext using the Default Database type
+func QueryAllTContext[T any](ctx context.Context, db DBContext, query string, args ...interface{}) ([]*T, error) {
+	return For[T](Default).QueryAllContext(ctx, db, query, args...)
+}
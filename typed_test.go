@@ -0,0 +1,75 @@
+//go:build go1.18
+// +build go1.18
+
+package meddler
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+)
+
+func TestLoadT(t *testing.T) {
+	once.Do(setup)
+	insertAliceBob(t)
+
+	elt, err := LoadT[Person](db, "person", 2)
+	if err != nil {
+		t.Errorf("LoadT error on Bob: %v", err)
+		return
+	}
+	bob.ID = 2
+	personEqual(t, elt, bob)
+
+	if _, err := LoadT[Person](db, "person", 3); err != sql.ErrNoRows {
+		t.Errorf("LoadT on missing row: want %v, got %v", sql.ErrNoRows, err)
+	}
+	db.Exec("delete from person")
+}
+
+func TestQueryRowT(t *testing.T) {
+	once.Do(setup)
+	insertAliceBob(t)
+
+	elt, err := QueryRowT[Person](db, "select * from person where name = ?", "Bob")
+	if err != nil {
+		t.Errorf("QueryRowT error on Bob: %v", err)
+		return
+	}
+	bob.ID = 2
+	personEqual(t, elt, bob)
+
+	if elt, err := QueryRowT[Person](db, "select * from person where name = ?", "Carol"); err != sql.ErrNoRows || elt != nil {
+		t.Errorf("QueryRowT on missing row: want nil, %v, got %v, %v", sql.ErrNoRows, elt, err)
+	}
+	db.Exec("delete from person")
+}
+
+func TestQueryAllT(t *testing.T) {
+	once.Do(setup)
+	insertAliceBob(t)
+
+	lst, err := For[Person](SQLite).QueryAllContext(context.Background(), db, "select * from person order by id")
+	if err != nil {
+		t.Errorf("QueryAllContext error: %v", err)
+		return
+	}
+	if len(lst) != 2 {
+		t.Errorf("QueryAllContext: expected 2 people, found %d", len(lst))
+		return
+	}
+	if lst[0].Name != "Alice" {
+		t.Errorf("QueryAllContext: expected Alice first, found %s", lst[0].Name)
+	}
+	bob.ID = 2
+	personEqual(t, lst[1], bob)
+
+	lst, err = QueryAllT[Person](db, "select * from person where name = ?", "Carol")
+	if err != nil {
+		t.Errorf("QueryAllT error: %v", err)
+	}
+	if lst == nil || len(lst) != 0 {
+		t.Errorf("QueryAllT with no rows: want an empty slice, got %#v", lst)
+	}
+	db.Exec("delete from person")
+}